@@ -0,0 +1,310 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// placeholder in args column, e.g. {sample.fq1}, {barcode.list}, {outdir}, {step.bwaMem.output}
+var placeholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// bare name of args column, a legacy name or column of input list, never a literal
+var bareName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// max nested {step.*.*} expansion
+const maxArgDepth = 8
+
+// legacy bare names of args column, kept for old allSteps.tsv
+var legacyArgs = map[string]map[string]string{
+	"batch": {
+		"list":      "{list}",
+		"laneInput": "{laneInput}",
+	},
+	"barcode": {
+		"barcode": "{barcode}",
+		"fq1":     "{barcode.fq1}",
+		"fq2":     "{barcode.fq2}",
+		"list":    "{barcode.list}",
+	},
+}
+
+// {sample.*} not from input list columns
+var sampleFields = map[string]bool{"sampleID": true, "id": true, "lanes": true, "laneList": true}
+
+// job context to resolve placeholders
+type ArgContext struct {
+	Task     *Task
+	Info     Info
+	Sample   *Sample
+	Barcode  *Barcode
+	TaskList map[string]*Task
+}
+
+func newArgContext(task *Task, info Info, jobName string, taskList map[string]*Task) (ctx *ArgContext, err error) {
+	ctx = &ArgContext{
+		Task:     task,
+		Info:     info,
+		TaskList: taskList,
+	}
+	switch task.TaskType {
+	case "sample":
+		var ok bool
+		ctx.Sample, ok = info.SampleMap[jobName]
+		if !ok {
+			return nil, fmt.Errorf("can not find sample[%s]", jobName)
+		}
+		ctx.Barcode = info.BarcodeMap[ctx.Sample.barcode]
+	case "barcode":
+		var ok bool
+		ctx.Barcode, ok = info.BarcodeMap[jobName]
+		if !ok {
+			return nil, fmt.Errorf("can not find barcode[%s]", jobName)
+		}
+	case "batch":
+	default:
+		return nil, fmt.Errorf("not support task type:%s", task.TaskType)
+	}
+	return
+}
+
+// ResolveArgs resolve all args of task in ctx, empty arg is skipped
+func (ctx *ArgContext) ResolveArgs(args []string) (values []string, err error) {
+	for _, arg := range args {
+		if arg == "" {
+			continue
+		}
+		var value string
+		value, err = ctx.resolveArg(arg)
+		if err != nil {
+			return nil, fmt.Errorf("Task[%s] arg[%s]:%v", ctx.Task.TaskName, arg, err)
+		}
+		values = append(values, value)
+	}
+	return
+}
+
+// ResolvePaths resolve path templates, e.g. outputs column, without legacy bare names
+func (ctx *ArgContext) ResolvePaths(templates []string) (paths []string, err error) {
+	for _, template := range templates {
+		var path string
+		path, err = ctx.Resolve(template, 0)
+		if err != nil {
+			return nil, fmt.Errorf("Task[%s] path[%s]:%v", ctx.Task.TaskName, template, err)
+		}
+		paths = append(paths, path)
+	}
+	return
+}
+
+// Eval evaluate when condition: "A == B", "A != B", or "A" for non-empty
+func (ctx *ArgContext) Eval(when string) (bool, error) {
+	for _, op := range []string{"==", "!="} {
+		var sides = strings.SplitN(when, op, 2)
+		if len(sides) != 2 {
+			continue
+		}
+		var left, err = ctx.Resolve(strings.TrimSpace(sides[0]), 0)
+		if err != nil {
+			return false, fmt.Errorf("Task[%s] when[%s]:%v", ctx.Task.TaskName, when, err)
+		}
+		right, err := ctx.Resolve(strings.TrimSpace(sides[1]), 0)
+		if err != nil {
+			return false, fmt.Errorf("Task[%s] when[%s]:%v", ctx.Task.TaskName, when, err)
+		}
+		return (left == right) == (op == "=="), nil
+	}
+	var value, err = ctx.Resolve(strings.TrimSpace(when), 0)
+	if err != nil {
+		return false, fmt.Errorf("Task[%s] when[%s]:%v", ctx.Task.TaskName, when, err)
+	}
+	return value != "", nil
+}
+
+// resolveArg resolve one arg: 'TEXT' is literal TEXT, a bare name is a legacy name or column of input list,
+// and others are resolved by Resolve
+func (ctx *ArgContext) resolveArg(arg string) (string, error) {
+	if len(arg) >= 2 && strings.HasPrefix(arg, "'") && strings.HasSuffix(arg, "'") {
+		return arg[1 : len(arg)-1], nil
+	}
+	if !bareName.MatchString(arg) {
+		return ctx.Resolve(arg, 0)
+	}
+	var template, err = ctx.legacy(arg)
+	if err != nil {
+		return "", err
+	}
+	return ctx.Resolve(template, 0)
+}
+
+// legacy map bare name to placeholder, error for unknown name, e.g. typo or column missing in input list
+func (ctx *ArgContext) legacy(arg string) (string, error) {
+	if ctx.Task.TaskType == "sample" {
+		// sample task took any column of input list
+		if _, ok := ctx.Sample.info[arg]; ok {
+			return "{sample." + arg + "}", nil
+		}
+	} else if v, ok := legacyArgs[ctx.Task.TaskType][arg]; ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("unknown name %s, use {%s} for placeholder or '%s' for literal", arg, arg, arg)
+}
+
+// Resolve replace every placeholder in arg, text out of braces is literal
+func (ctx *ArgContext) Resolve(arg string, depth int) (string, error) {
+	if depth > maxArgDepth {
+		return "", fmt.Errorf("too deep placeholder:%s", arg)
+	}
+	if strings.ContainsAny(placeholder.ReplaceAllString(arg, ""), "{}") {
+		return "", fmt.Errorf("unbalanced brace:%s", arg)
+	}
+	var err error
+	var value = placeholder.ReplaceAllStringFunc(arg, func(m string) string {
+		if err != nil {
+			return ""
+		}
+		var v string
+		v, err = ctx.lookup(m[1:len(m)-1], depth)
+		return v
+	})
+	return value, err
+}
+
+func (ctx *ArgContext) lookup(name string, depth int) (string, error) {
+	var keys = strings.Split(name, ".")
+	switch keys[0] {
+	case "outdir":
+		if len(keys) == 1 {
+			return *outDir, nil
+		}
+	case "local", "pipeline":
+		if len(keys) == 1 {
+			return *localpath, nil
+		}
+	case "list":
+		if len(keys) == 1 {
+			return filepath.Join(*outDir, "input.list"), nil
+		}
+	case "laneInput", "lane":
+		if len(keys) == 1 {
+			return *lane, nil
+		}
+	case "taskName":
+		if len(keys) == 1 {
+			return ctx.Task.TaskName, nil
+		}
+	case "sampleID":
+		if len(keys) == 1 && ctx.Sample != nil {
+			return ctx.Sample.sampleID, nil
+		}
+	case "sampleDir":
+		if len(keys) == 1 && ctx.Sample != nil {
+			return filepath.Join(*outDir, ctx.Sample.sampleID), nil
+		}
+	case "dir":
+		// sample dir in sample task, else batch dir
+		if len(keys) == 2 {
+			if _, ok := layout.Sample[keys[1]]; ok && ctx.Sample != nil {
+				return layout.sampleDir(ctx.Sample.sampleID, keys[1]), nil
+			}
+			if _, ok := layout.Batch[keys[1]]; ok {
+				return layout.batchDir(keys[1]), nil
+			}
+		}
+	case "layout":
+		// relative path of sample dir, for tasks writing into sample dirs
+		if len(keys) == 2 && layout.Sample[keys[1]] != "" {
+			return layout.Sample[keys[1]], nil
+		}
+	case "sample":
+		if ctx.Sample == nil {
+			return "", fmt.Errorf("{%s} not available in %s task", name, ctx.Task.TaskType)
+		}
+		if len(keys) == 2 {
+			return ctx.Sample.lookup(keys[1])
+		}
+	case "barcode":
+		if ctx.Barcode == nil {
+			return "", fmt.Errorf("{%s} not available in %s task", name, ctx.Task.TaskType)
+		}
+		if len(keys) == 1 {
+			return ctx.Barcode.barcode, nil
+		}
+		if len(keys) == 2 {
+			return ctx.Barcode.lookup(keys[1])
+		}
+	case "step":
+		if len(keys) == 3 {
+			return ctx.lookupStep(keys[1], keys[2], depth)
+		}
+	}
+	return "", fmt.Errorf("can not resolve {%s}", name)
+}
+
+// {step.NAME.FIELD}: column FIELD of step NAME, resolved in current job
+func (ctx *ArgContext) lookupStep(stepName, field string, depth int) (string, error) {
+	var task, ok = ctx.TaskList[stepName]
+	if !ok {
+		return "", fmt.Errorf("can not find step[%s]", stepName)
+	}
+	if field == "output" {
+		// first declared output
+		if len(task.TaskOutputs) == 0 {
+			return "", fmt.Errorf("step[%s] has no outputs", stepName)
+		}
+		return ctx.Resolve(task.TaskOutputs[0], depth+1)
+	}
+	value, ok := task.TaskInfo[field]
+	if !ok || value == "" {
+		return "", fmt.Errorf("step[%s] has no %s", stepName, field)
+	}
+	return ctx.Resolve(value, depth+1)
+}
+
+// split comma separated column, skip empty item
+func splitList(str string) (list []string) {
+	for _, item := range strings.Split(str, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return
+}
+
+func (sample *Sample) lookup(key string) (string, error) {
+	switch key {
+	case "sampleID", "id":
+		return sample.sampleID, nil
+	case "sampleNum":
+		return sample.sampleNum, nil
+	case "barcode":
+		return sample.barcode, nil
+	case "primer":
+		return sample.primer, nil
+	case "lanes":
+		return sample.laneNames(), nil
+	case "laneList":
+		return sample.laneList, nil
+	}
+	var value, ok = sample.info[key]
+	if !ok {
+		return "", fmt.Errorf("sample[%s] has no column %s", sample.sampleID, key)
+	}
+	return value, nil
+}
+
+func (barcode *Barcode) lookup(key string) (string, error) {
+	switch key {
+	case "barcode", "id":
+		return barcode.barcode, nil
+	case "list":
+		return barcode.list, nil
+	case "fq1":
+		return barcode.fq1, nil
+	case "fq2":
+		return barcode.fq2, nil
+	}
+	return "", fmt.Errorf("barcode[%s] has no %s", barcode.barcode, key)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func testArgContext(taskType string) *ArgContext {
+	*outDir = "/out"
+	var sample = &Sample{
+		sampleID:  "S1",
+		sampleNum: "1",
+		barcode:   "B1",
+		primer:    "ACGTACG",
+		info:      map[string]string{"sampleID": "S1", "fq1": "/in/S1_1.fq.gz", "group": "case"},
+	}
+	var barcode = &Barcode{barcode: "B1", list: "/out/B1.list", fq1: "/in/B1_1.fq.gz", fq2: "/in/B1_2.fq.gz"}
+	var bwa = &Task{
+		TaskName:    "bwaMem",
+		TaskType:    "sample",
+		TaskInfo:    map[string]string{"mem": "8"},
+		TaskOutputs: []string{"{sampleDir}/bwa/{sampleID}.bam"},
+	}
+	var ctx = &ArgContext{
+		Task:     &Task{TaskName: "test", TaskType: taskType},
+		TaskList: map[string]*Task{"bwaMem": bwa},
+	}
+	switch taskType {
+	case "sample":
+		ctx.Sample, ctx.Barcode = sample, barcode
+	case "barcode":
+		ctx.Barcode = barcode
+	}
+	return ctx
+}
+
+func TestResolve(t *testing.T) {
+	var tests = []struct {
+		taskType, arg, value string
+		err                  bool
+	}{
+		{"sample", "{outdir}", "/out", false},
+		{"sample", "{sampleID}", "S1", false},
+		{"sample", "{sampleDir}/x.txt", "/out/S1/x.txt", false},
+		{"sample", "{sample.fq1}", "/in/S1_1.fq.gz", false},
+		{"sample", "{sample.group}", "case", false},
+		{"sample", "{barcode.list}", "/out/B1.list", false},
+		{"sample", "{step.bwaMem.output}", "/out/S1/bwa/S1.bam", false},
+		{"sample", "{step.bwaMem.mem}", "8", false},
+		{"sample", "{dir.raw}", "/out/S1/raw", false},
+		{"sample", "{sample.missing}", "", true},
+		{"sample", "{step.none.output}", "", true},
+		{"sample", "{unknown}", "", true},
+		{"sample", "{sampleID", "", true},
+		{"barcode", "{barcode}", "B1", false},
+		{"barcode", "{barcode.fq2}", "/in/B1_2.fq.gz", false},
+		{"barcode", "{sample.fq1}", "", true},
+		{"batch", "{list}", "/out/input.list", false},
+		{"batch", "{barcode.list}", "", true},
+	}
+	for _, test := range tests {
+		var value, err = testArgContext(test.taskType).Resolve(test.arg, 0)
+		if (err != nil) != test.err || value != test.value {
+			t.Errorf("%s Resolve(%s)=%q,%v want %q,err:%v", test.taskType, test.arg, value, err, test.value, test.err)
+		}
+	}
+}
+
+func TestResolveArgs(t *testing.T) {
+	var tests = []struct {
+		taskType, arg, value, err string
+	}{
+		{"sample", "fq1", "/in/S1_1.fq.gz", ""},
+		{"sample", "sampleId", "", "unknown name sampleId"},
+		{"sample", "'raw'", "raw", ""},
+		{"sample", "hg19.fa", "hg19.fa", ""},
+		{"sample", "10", "10", ""},
+		{"barcode", "list", "/out/B1.list", ""},
+		{"barcode", "lsit", "", "unknown name lsit"},
+		{"batch", "list", "/out/input.list", ""},
+	}
+	for _, test := range tests {
+		var values, err = testArgContext(test.taskType).ResolveArgs([]string{test.arg})
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s ResolveArgs(%s) error:%v want %s", test.taskType, test.arg, err, test.err)
+			}
+			continue
+		}
+		if err != nil || len(values) != 1 || values[0] != test.value {
+			t.Errorf("%s ResolveArgs(%s)=%v,%v want %s", test.taskType, test.arg, values, err, test.value)
+		}
+	}
+}
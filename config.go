@@ -0,0 +1,709 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/liserjrqlxue/goUtil/osUtil"
+	"github.com/liserjrqlxue/goUtil/simpleUtil"
+	simple_util "github.com/liserjrqlxue/simple-util"
+	"gopkg.in/yaml.v3"
+)
+
+// StepConfig is one step of pipeline, mapped onto Task
+type StepConfig struct {
+	Name       string            `yaml:"name" json:"name"`
+	Type       string            `yaml:"type" json:"type"`
+	Prior      []string          `yaml:"prior,omitempty" json:"prior,omitempty"`
+	Args       []string          `yaml:"args,omitempty" json:"args,omitempty"`
+	Mem        float64           `yaml:"mem" json:"mem"`
+	Thread     int               `yaml:"thread" json:"thread"`
+	Disk       float64           `yaml:"disk,omitempty" json:"disk,omitempty"`
+	SubmitArgs []string          `yaml:"submitArgs,omitempty" json:"submitArgs,omitempty"`
+	Inputs     []string          `yaml:"inputs,omitempty" json:"inputs,omitempty"`
+	Outputs    []string          `yaml:"outputs,omitempty" json:"outputs,omitempty"`
+	Temp       []string          `yaml:"temp,omitempty" json:"temp,omitempty"`
+	Deliver    []string          `yaml:"deliver,omitempty" json:"deliver,omitempty"`
+	Env        map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+	When       string            `yaml:"when,omitempty" json:"when,omitempty"`
+	Scratch    bool              `yaml:"scratch,omitempty" json:"scratch,omitempty"`
+	// where step defined, for error message
+	file string
+	line int
+}
+
+// StepOverride override settings of an included step, unset field keeps included value
+type StepOverride struct {
+	Prior      []string          `yaml:"prior,omitempty" json:"prior,omitempty"`
+	Args       []string          `yaml:"args,omitempty" json:"args,omitempty"`
+	Mem        *float64          `yaml:"mem,omitempty" json:"mem,omitempty"`
+	Thread     *int              `yaml:"thread,omitempty" json:"thread,omitempty"`
+	Disk       *float64          `yaml:"disk,omitempty" json:"disk,omitempty"`
+	SubmitArgs []string          `yaml:"submitArgs,omitempty" json:"submitArgs,omitempty"`
+	Inputs     []string          `yaml:"inputs,omitempty" json:"inputs,omitempty"`
+	Outputs    []string          `yaml:"outputs,omitempty" json:"outputs,omitempty"`
+	Temp       []string          `yaml:"temp,omitempty" json:"temp,omitempty"`
+	Deliver    []string          `yaml:"deliver,omitempty" json:"deliver,omitempty"`
+	Env        map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+	When       *string           `yaml:"when,omitempty" json:"when,omitempty"`
+	Scratch    *bool             `yaml:"scratch,omitempty" json:"scratch,omitempty"`
+}
+
+// PipelineConfig is pipeline definition of yaml/json format
+type PipelineConfig struct {
+	Include  []string                `yaml:"include,omitempty" json:"include,omitempty"`
+	Steps    []StepConfig            `yaml:"steps,omitempty" json:"steps,omitempty"`
+	Override map[string]StepOverride `yaml:"override,omitempty" json:"override,omitempty"`
+	Layout   *Layout                 `yaml:"layout,omitempty" json:"layout,omitempty"`
+}
+
+// extensions of profile, in search order
+var profileExts = []string{".yaml", ".yml", ".json", ".tsv"}
+
+var taskTypes = []string{"batch", "barcode", "sample"}
+
+// columns of tsv format, in output order
+var stepColumns = []string{"name", "mem", "thread", "disk", "type", "prior", "args", "submitArgs", "inputs", "outputs", "temp", "deliver", "env", "when", "scratch"}
+
+// field kinds of yaml/json format, keep same as etc/pipeline.schema.json
+var stepFields = map[string]string{
+	"name":       "string",
+	"type":       "string",
+	"prior":      "list",
+	"args":       "list",
+	"mem":        "float",
+	"thread":     "int",
+	"disk":       "float",
+	"submitArgs": "list",
+	"inputs":     "list",
+	"outputs":    "list",
+	"temp":       "list",
+	"deliver":    "list",
+	"env":        "map",
+	"when":       "string",
+	"scratch":    "bool",
+}
+
+// ConfigError collect all errors of pipeline files with line number
+type ConfigError struct {
+	File   string
+	Errors []string
+}
+
+func (e *ConfigError) add(line int, format string, a ...interface{}) {
+	e.addAt(e.File, line, format, a...)
+}
+
+func (e *ConfigError) addAt(file string, line int, format string, a ...interface{}) {
+	e.Errors = append(e.Errors, fmt.Sprintf("%s:%d: %s", file, line, fmt.Sprintf(format, a...)))
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid pipeline:\n" + strings.Join(e.Errors, "\n")
+}
+
+func (e *ConfigError) err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// LoadPipeline load pipeline of tsv, yaml or json format by extension, with includes and overrides,
+// layout sections not set by pipeline are default
+func LoadPipeline(fileName string) (steps []StepConfig, dirLayout Layout, err error) {
+	var cfgErr = &ConfigError{File: fileName}
+	steps, dirLayout = loadPipeline(fileName, nil, cfgErr)
+	dirLayout = defaultLayout.merge(&dirLayout)
+	if len(cfgErr.Errors) == 0 {
+		validateSteps(steps, cfgErr)
+		dirLayout.validate(fileName, cfgErr)
+	}
+	return steps, dirLayout, cfgErr.err()
+}
+
+// LoadProfile load named profile from dir, e.g. wes -> dir/wes.yaml
+func LoadProfile(name, dir string) (steps []StepConfig, dirLayout Layout, err error) {
+	fileName, err := findProfile(name, dir)
+	if err != nil {
+		return
+	}
+	log.Printf("load profile[%s]:%s", name, fileName)
+	return LoadPipeline(fileName)
+}
+
+// findProfile return file of profile name in dir, name can also be a file path
+func findProfile(name, dir string) (string, error) {
+	if simple_util.FileExists(name) {
+		return name, nil
+	}
+	var candidates []string
+	for _, ext := range profileExts {
+		var fileName = filepath.Join(dir, name+ext)
+		if simple_util.FileExists(fileName) {
+			return fileName, nil
+		}
+		candidates = append(candidates, fileName)
+	}
+	return "", fmt.Errorf("can not find profile[%s] in %v", name, candidates)
+}
+
+// loadPipeline load fileName recursively, stack is include chain to detect cycle,
+// return layout sections set by fileName or its includes
+func loadPipeline(fileName string, stack []string, cfgErr *ConfigError) (steps []StepConfig, dirLayout Layout) {
+	var abs, _ = filepath.Abs(fileName)
+	if contains(stack, abs) {
+		cfgErr.addAt(fileName, 1, "include cycle:%s", strings.Join(append(stack, abs), " -> "))
+		return
+	}
+	stack = append(stack, abs)
+
+	switch filepath.Ext(fileName) {
+	case ".yaml", ".yml", ".json":
+	default:
+		steps = loadPipelineTsv(fileName, cfgErr)
+		checkDupSteps(steps, cfgErr)
+		return
+	}
+
+	config, lines := loadPipelineYaml(fileName, cfgErr)
+	checkDupSteps(config.Steps, cfgErr)
+	for _, include := range config.Include {
+		var includeFile, err = findProfile(include, filepath.Dir(fileName))
+		if err != nil {
+			cfgErr.addAt(fileName, lines["include"], "%v", err)
+			continue
+		}
+		var includeSteps, includeLayout = loadPipeline(includeFile, stack, cfgErr)
+		steps = mergeSteps(steps, includeSteps)
+		dirLayout = dirLayout.merge(&includeLayout)
+	}
+	steps = mergeSteps(steps, config.Steps)
+	dirLayout = dirLayout.merge(config.Layout)
+	var names []string
+	for name := range config.Override {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var found bool
+		for i := range steps {
+			if steps[i].Name == name {
+				steps[i].override(config.Override[name])
+				found = true
+			}
+		}
+		if !found {
+			cfgErr.addAt(fileName, lines["override."+name], "override step[%s] not found", name)
+		}
+	}
+	return
+}
+
+// step name should be unique in one file
+func checkDupSteps(steps []StepConfig, cfgErr *ConfigError) {
+	var seen = make(map[string]bool)
+	for _, step := range steps {
+		if step.Name != "" && seen[step.Name] {
+			cfgErr.addAt(step.file, step.line, "dup step name:%s", step.Name)
+		}
+		seen[step.Name] = true
+	}
+}
+
+// mergeSteps append steps, step with same name replace previous one in place
+func mergeSteps(steps, newSteps []StepConfig) []StepConfig {
+	for _, newStep := range newSteps {
+		var replaced bool
+		for i := range steps {
+			if newStep.Name != "" && steps[i].Name == newStep.Name {
+				steps[i] = newStep
+				replaced = true
+			}
+		}
+		if !replaced {
+			steps = append(steps, newStep)
+		}
+	}
+	return steps
+}
+
+func (step *StepConfig) override(o StepOverride) {
+	if o.Prior != nil {
+		step.Prior = o.Prior
+	}
+	if o.Args != nil {
+		step.Args = o.Args
+	}
+	if o.Mem != nil {
+		step.Mem = *o.Mem
+	}
+	if o.Thread != nil {
+		step.Thread = *o.Thread
+	}
+	if o.Disk != nil {
+		step.Disk = *o.Disk
+	}
+	if o.SubmitArgs != nil {
+		step.SubmitArgs = o.SubmitArgs
+	}
+	if o.Inputs != nil {
+		step.Inputs = o.Inputs
+	}
+	if o.Outputs != nil {
+		step.Outputs = o.Outputs
+	}
+	if o.Temp != nil {
+		step.Temp = o.Temp
+	}
+	if o.Deliver != nil {
+		step.Deliver = o.Deliver
+	}
+	if o.When != nil {
+		step.When = *o.When
+	}
+	if o.Scratch != nil {
+		step.Scratch = *o.Scratch
+	}
+	for key, value := range o.Env {
+		if step.Env == nil {
+			step.Env = make(map[string]string)
+		}
+		step.Env[key] = value
+	}
+}
+
+func loadPipelineTsv(fileName string, cfgErr *ConfigError) (steps []StepConfig) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		cfgErr.addAt(fileName, 0, "%v", err)
+		return
+	}
+	defer simpleUtil.DeferClose(file)
+
+	var scanner = bufio.NewScanner(file)
+	var title []string
+	var lineNo int
+	for scanner.Scan() {
+		lineNo++
+		var line = scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var array = strings.Split(line, "\t")
+		for i := range array {
+			array[i] = strings.TrimSpace(array[i])
+		}
+		if title == nil {
+			title = array
+			for _, key := range title {
+				if _, ok := stepFields[key]; !ok {
+					cfgErr.addAt(fileName, lineNo, "unknown column:%q", key)
+				}
+			}
+			continue
+		}
+		if len(array) > len(title) {
+			cfgErr.addAt(fileName, lineNo, "%d columns more than title %d", len(array), len(title))
+			continue
+		}
+		var item = make(map[string]string)
+		for i, key := range title {
+			if i < len(array) {
+				item[key] = array[i]
+			}
+		}
+		steps = append(steps, item2step(item, fileName, lineNo, cfgErr))
+	}
+	if err = scanner.Err(); err != nil {
+		cfgErr.addAt(fileName, lineNo, "%v", err)
+	}
+	return
+}
+
+func item2step(item map[string]string, fileName string, lineNo int, cfgErr *ConfigError) StepConfig {
+	var step = StepConfig{
+		Name:       item["name"],
+		Type:       item["type"],
+		Prior:      splitList(item["prior"]),
+		Args:       splitList(item["args"]),
+		SubmitArgs: sep.Split(strings.TrimSpace(item["submitArgs"]), -1),
+		Inputs:     splitList(item["inputs"]),
+		Outputs:    splitList(item["outputs"]),
+		Temp:       splitList(item["temp"]),
+		Deliver:    splitList(item["deliver"]),
+		When:       item["when"],
+		file:       fileName,
+		line:       lineNo,
+	}
+	if step.SubmitArgs[0] == "" {
+		step.SubmitArgs = nil
+	}
+	var err error
+	if item["scratch"] != "" {
+		step.Scratch, err = strconv.ParseBool(item["scratch"])
+		if err != nil {
+			cfgErr.addAt(fileName, lineNo, "scratch of step[%s] is not bool:%q", step.Name, item["scratch"])
+		}
+	}
+	if item["thread"] != "" {
+		step.Thread, err = strconv.Atoi(item["thread"])
+		if err != nil {
+			cfgErr.addAt(fileName, lineNo, "thread of step[%s] is not integer:%q", step.Name, item["thread"])
+		}
+	}
+	// mem in G may be fractional, e.g. 2.5 for vf=2.5G
+	for key, value := range map[string]*float64{"mem": &step.Mem, "disk": &step.Disk} {
+		if item[key] == "" {
+			continue
+		}
+		*value, err = strconv.ParseFloat(item[key], 64)
+		if err != nil {
+			cfgErr.addAt(fileName, lineNo, "%s of step[%s] is not number:%q", key, step.Name, item[key])
+		}
+	}
+	for _, kv := range splitList(item["env"]) {
+		var kvs = strings.SplitN(kv, "=", 2)
+		if len(kvs) != 2 {
+			cfgErr.addAt(fileName, lineNo, "env of step[%s] is not KEY=VALUE:%q", step.Name, kv)
+			continue
+		}
+		if step.Env == nil {
+			step.Env = make(map[string]string)
+		}
+		step.Env[kvs[0]] = kvs[1]
+	}
+	return step
+}
+
+// loadPipelineYaml load one yaml/json file, return line of include and each override, and layout keeps its own lines
+func loadPipelineYaml(fileName string, cfgErr *ConfigError) (config PipelineConfig, lines map[string]int) {
+	lines = make(map[string]int)
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		cfgErr.addAt(fileName, 0, "%v", err)
+		return
+	}
+	var root yaml.Node
+	if err = yaml.Unmarshal(b, &root); err != nil {
+		cfgErr.addAt(fileName, 0, "%v", err)
+		return
+	}
+	var fileErr = &ConfigError{File: fileName}
+	var stepLines = validateYaml(&root, fileErr, lines)
+	if len(fileErr.Errors) > 0 {
+		cfgErr.Errors = append(cfgErr.Errors, fileErr.Errors...)
+		return
+	}
+	if err = root.Decode(&config); err != nil {
+		cfgErr.addAt(fileName, 0, "%v", err)
+		return
+	}
+	for i := range config.Steps {
+		config.Steps[i].file = fileName
+		config.Steps[i].line = stepLines[i]
+	}
+	if config.Layout != nil {
+		config.Layout.pos = make(map[string]filePos)
+		for key, line := range lines {
+			if strings.HasPrefix(key, "layout.") {
+				config.Layout.pos[strings.TrimPrefix(key, "layout.")] = filePos{fileName, line}
+			}
+		}
+	}
+	return
+}
+
+// validateYaml check structure of yaml/json document, return line of each step
+func validateYaml(root *yaml.Node, cfgErr *ConfigError, lines map[string]int) (stepLines []int) {
+	if len(root.Content) == 0 {
+		cfgErr.add(root.Line, "empty pipeline")
+		return
+	}
+	var doc = root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		cfgErr.add(doc.Line, "pipeline should be a mapping with steps")
+		return
+	}
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		var key, value = doc.Content[i], doc.Content[i+1]
+		lines[key.Value] = key.Line
+		switch key.Value {
+		case "steps":
+			if value.Kind != yaml.SequenceNode {
+				cfgErr.add(value.Line, "steps should be a list")
+				continue
+			}
+			for _, stepNode := range value.Content {
+				stepLines = append(stepLines, stepNode.Line)
+				validateStepNode(stepNode, cfgErr, false)
+			}
+		case "include":
+			validateList(key.Value, value, cfgErr)
+		case "layout":
+			validateLayoutNode(value, cfgErr, lines)
+		case "override":
+			if value.Kind != yaml.MappingNode {
+				cfgErr.add(value.Line, "override should be a mapping of step name")
+				continue
+			}
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				lines["override."+value.Content[j].Value] = value.Content[j].Line
+				validateStepNode(value.Content[j+1], cfgErr, true)
+			}
+		default:
+			cfgErr.add(key.Line, "unknown key:%q", key.Value)
+		}
+	}
+	if lines["steps"] == 0 && lines["include"] == 0 {
+		cfgErr.add(doc.Line, "missing steps or include")
+	}
+	return
+}
+
+func validateList(name string, value *yaml.Node, cfgErr *ConfigError) {
+	if value.Kind != yaml.SequenceNode {
+		cfgErr.add(value.Line, "%s should be a list", name)
+		return
+	}
+	for _, item := range value.Content {
+		if item.Kind != yaml.ScalarNode {
+			cfgErr.add(item.Line, "item of %s should be a string", name)
+		}
+	}
+}
+
+// validateLayoutNode check layout is a mapping of batch and sample, each a mapping of dir name to path,
+// line of each section and dir is kept as layout.batch and layout.batch.NAME of lines
+func validateLayoutNode(node *yaml.Node, cfgErr *ConfigError, lines map[string]int) {
+	if node.Kind != yaml.MappingNode {
+		cfgErr.add(node.Line, "layout should be a mapping of batch and sample")
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var key, value = node.Content[i], node.Content[i+1]
+		if key.Value != "batch" && key.Value != "sample" {
+			cfgErr.add(key.Line, "unknown layout key:%q", key.Value)
+			continue
+		}
+		if value.Kind != yaml.MappingNode {
+			cfgErr.add(value.Line, "layout.%s should be a mapping of dir name to path", key.Value)
+			continue
+		}
+		lines["layout."+key.Value] = key.Line
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			lines["layout."+key.Value+"."+value.Content[j].Value] = value.Content[j].Line
+			if value.Content[j+1].Kind != yaml.ScalarNode {
+				cfgErr.add(value.Content[j+1].Line, "layout.%s.%s should be a string", key.Value, value.Content[j].Value)
+			}
+		}
+	}
+}
+
+// validateStepNode check keys and value kinds of a step, or of an override without name and type
+func validateStepNode(node *yaml.Node, cfgErr *ConfigError, override bool) {
+	if node.Kind != yaml.MappingNode {
+		cfgErr.add(node.Line, "step should be a mapping")
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var key, value = node.Content[i], node.Content[i+1]
+		var kind, ok = stepFields[key.Value]
+		if !ok || (override && (key.Value == "name" || key.Value == "type")) {
+			cfgErr.add(key.Line, "unknown step key:%q", key.Value)
+			continue
+		}
+		switch kind {
+		case "string":
+			if value.Kind != yaml.ScalarNode {
+				cfgErr.add(value.Line, "%s should be a string", key.Value)
+			}
+		case "int":
+			if _, err := strconv.Atoi(value.Value); value.Kind != yaml.ScalarNode || err != nil {
+				cfgErr.add(value.Line, "%s should be an integer", key.Value)
+			}
+		case "float":
+			if _, err := strconv.ParseFloat(value.Value, 64); value.Kind != yaml.ScalarNode || err != nil {
+				cfgErr.add(value.Line, "%s should be a number", key.Value)
+			}
+		case "bool":
+			if _, err := strconv.ParseBool(value.Value); value.Kind != yaml.ScalarNode || err != nil {
+				cfgErr.add(value.Line, "%s should be a bool", key.Value)
+			}
+		case "list":
+			validateList(key.Value, value, cfgErr)
+		case "map":
+			if value.Kind != yaml.MappingNode {
+				cfgErr.add(value.Line, "%s should be a mapping", key.Value)
+			}
+		}
+	}
+}
+
+// validateSteps check required fields, type and prior of merged steps
+func validateSteps(steps []StepConfig, cfgErr *ConfigError) {
+	var names = make(map[string]bool)
+	for _, step := range steps {
+		names[step.Name] = true
+	}
+	var seen = make(map[string]bool)
+	for _, step := range steps {
+		if step.Name == "" {
+			cfgErr.addAt(step.file, step.line, "missing name")
+		} else if seen[step.Name] {
+			cfgErr.addAt(step.file, step.line, "dup step name:%s", step.Name)
+		}
+		seen[step.Name] = true
+		if !contains(taskTypes, step.Type) {
+			cfgErr.addAt(step.file, step.line, "type of step[%s] should be one of %v:%q", step.Name, taskTypes, step.Type)
+		}
+		if step.Scratch && len(step.Outputs) == 0 {
+			cfgErr.addAt(step.file, step.line, "scratch step[%s] should declare outputs to copy back", step.Name)
+		}
+		if step.Disk < 0 {
+			cfgErr.addAt(step.file, step.line, "disk of step[%s] should not be negative:%v", step.Name, step.Disk)
+		}
+		for _, temp := range step.Temp {
+			if !contains(step.Outputs, temp) {
+				cfgErr.addAt(step.file, step.line, "temp of step[%s] should be one of outputs:%q", step.Name, temp)
+			}
+		}
+		for _, deliver := range step.Deliver {
+			if !contains(step.Outputs, deliver) {
+				cfgErr.addAt(step.file, step.line, "deliver of step[%s] should be one of outputs:%q", step.Name, deliver)
+			}
+			if contains(step.Temp, deliver) {
+				cfgErr.addAt(step.file, step.line, "deliver of step[%s] can not be temp:%q", step.Name, deliver)
+			}
+		}
+		for _, prior := range step.Prior {
+			if !names[prior] {
+				cfgErr.addAt(step.file, step.line, "can not find prior[%s] of step[%s]", prior, step.Name)
+			}
+		}
+	}
+}
+
+// Item convert step to a row of tsv format, used by Task and libIM
+func (step StepConfig) Item() map[string]string {
+	var item = map[string]string{
+		"name":       step.Name,
+		"type":       step.Type,
+		"prior":      strings.Join(step.Prior, ","),
+		"args":       strings.Join(step.Args, ","),
+		"mem":        strconv.FormatFloat(step.Mem, 'g', -1, 64),
+		"thread":     strconv.Itoa(step.Thread),
+		"submitArgs": strings.Join(step.SubmitArgs, " "),
+		"inputs":     strings.Join(step.Inputs, ","),
+		"outputs":    strings.Join(step.Outputs, ","),
+		"temp":       strings.Join(step.Temp, ","),
+		"deliver":    strings.Join(step.Deliver, ","),
+		"env":        strings.Join(step.envList(), ","),
+		"when":       step.When,
+	}
+	if step.Disk != 0 {
+		item["disk"] = strconv.FormatFloat(step.Disk, 'g', -1, 64)
+	}
+	if step.Scratch {
+		item["scratch"] = "true"
+	}
+	return item
+}
+
+// env as sorted KEY=VALUE
+func (step StepConfig) envList() (env []string) {
+	for key, value := range step.Env {
+		env = append(env, key+"="+value)
+	}
+	sort.Strings(env)
+	return
+}
+
+// hasBarcodeStep means samples are split from barcode
+func hasBarcodeStep(steps []StepConfig) bool {
+	for _, step := range steps {
+		if step.Type == "barcode" {
+			return true
+		}
+	}
+	return false
+}
+
+// ConvertPipeline write steps and layout to fileName, format by extension, tsv only support default layout
+func ConvertPipeline(steps []StepConfig, dirLayout Layout, fileName string) error {
+	var config = PipelineConfig{Steps: steps}
+	if !dirLayout.isDefault() {
+		config.Layout = &dirLayout
+	}
+	switch filepath.Ext(fileName) {
+	case ".yaml", ".yml":
+		var file = osUtil.Create(fileName)
+		defer simpleUtil.DeferClose(file)
+		var encoder = yaml.NewEncoder(file)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(config); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case ".json":
+		var b, err = json.MarshalIndent(config, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(fileName, append(b, '\n'), 0644)
+	default:
+		if config.Layout != nil {
+			return fmt.Errorf("layout can not be written to tsv:%s", fileName)
+		}
+		return writePipelineTsv(steps, fileName)
+	}
+}
+
+func writePipelineTsv(steps []StepConfig, fileName string) error {
+	for _, step := range steps {
+		for _, list := range [][]string{step.Prior, step.Args, step.Inputs, step.Outputs, step.Temp, step.Deliver, step.envList()} {
+			for _, item := range list {
+				if strings.ContainsAny(item, ",\t\n") {
+					return fmt.Errorf("step[%s]:%q can not be written to tsv", step.Name, item)
+				}
+			}
+		}
+	}
+	// only write used columns
+	var used = map[string]bool{"name": true, "mem": true, "thread": true, "type": true, "prior": true, "args": true}
+	var items []map[string]string
+	for _, step := range steps {
+		var item = step.Item()
+		for key, value := range item {
+			if value != "" {
+				used[key] = true
+			}
+		}
+		items = append(items, item)
+	}
+	var title []string
+	for _, key := range stepColumns {
+		if used[key] {
+			title = append(title, key)
+		}
+	}
+	var file = osUtil.Create(fileName)
+	defer simpleUtil.DeferClose(file)
+	var w = bufio.NewWriter(file)
+	fmt.Fprintln(w, strings.Join(title, "\t"))
+	for _, item := range items {
+		var row []string
+		for _, key := range title {
+			row = append(row, item[key])
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
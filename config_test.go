@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLayoutErrorLine(t *testing.T) {
+	var dir = t.TempDir()
+	var base = filepath.Join(dir, "base.yaml")
+	var top = filepath.Join(dir, "top.yaml")
+	var files = map[string]string{
+		base: `steps:
+  - name: A
+    type: sample
+layout:
+  sample:
+    raw: raw
+    shell: /abs/shell
+`,
+		top: `include: [base]
+layout:
+  batch:
+    barcode: barcode
+    bad-name: x
+`,
+	}
+	for fileName, content := range files {
+		if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var _, _, err = LoadPipeline(top)
+	if err == nil {
+		t.Fatal("no error of invalid layout")
+	}
+	for _, want := range []string{
+		top + ":3: layout.batch missing shell",
+		top + ":5: layout.batch name should be a shell variable name",
+		base + ":7: layout.sample.shell should be a relative path",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error:\n%v\nwant %s", err, want)
+		}
+	}
+}
+
+// stepFields and columns should follow step and override of etc/pipeline.schema.json
+func TestSchemaStepFields(t *testing.T) {
+	var b, err = os.ReadFile(filepath.Join("etc", "pipeline.schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type property struct {
+		Type string   `json:"type"`
+		Ref  string   `json:"$ref"`
+		Enum []string `json:"enum"`
+	}
+	var schema struct {
+		Definitions map[string]struct {
+			Properties map[string]property `json:"properties"`
+		} `json:"definitions"`
+	}
+	if err = json.Unmarshal(b, &schema); err != nil {
+		t.Fatal(err)
+	}
+	var kinds = map[string]string{"string": "string", "integer": "int", "number": "float", "boolean": "bool", "object": "map"}
+	for _, definition := range []string{"step", "override"} {
+		var properties = schema.Definitions[definition].Properties
+		for name, kind := range stepFields {
+			if definition == "override" && (name == "name" || name == "type") {
+				continue
+			}
+			var p, ok = properties[name]
+			var schemaKind = kinds[p.Type]
+			switch {
+			case p.Ref == "#/definitions/list":
+				schemaKind = "list"
+			case p.Enum != nil:
+				schemaKind = "string"
+			}
+			if !ok || schemaKind != kind {
+				t.Errorf("%s.%s of schema:%+v, stepFields:%s", definition, name, p, kind)
+			}
+		}
+		for name := range properties {
+			if _, ok := stepFields[name]; !ok {
+				t.Errorf("%s.%s of schema not in stepFields", definition, name)
+			}
+		}
+	}
+	for name := range stepFields {
+		if !contains(stepColumns, name) {
+			t.Errorf("%s not in stepColumns", name)
+		}
+	}
+}
+
+func TestLoadPipelineTsvMem(t *testing.T) {
+	var fileName = filepath.Join(t.TempDir(), "steps.tsv")
+	var content = "name\tmem\tthread\ttype\tprior\targs\nA\t2.5\t1\tbatch\t\t\nB\tx\t1.5\tbatch\tA\t\n"
+	if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var cfgErr = &ConfigError{File: fileName}
+	var steps = loadPipelineTsv(fileName, cfgErr)
+	if len(steps) != 2 || steps[0].Mem != 2.5 || steps[0].Item()["mem"] != "2.5" {
+		t.Errorf("steps:%+v", steps)
+	}
+	var errs = strings.Join(cfgErr.Errors, "\n")
+	for _, want := range []string{":3: mem of step[B] is not number", ":3: thread of step[B] is not integer"} {
+		if !strings.Contains(errs, fileName+want) {
+			t.Errorf("errors:%s\nwant %s", errs, want)
+		}
+	}
+}
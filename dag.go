@@ -0,0 +1,149 @@
+package main
+
+import (
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// inferPrior return tasks whose declared outputs match declared inputs of task, Make-style
+func inferPrior(task *Task, taskList map[string]*Task) (prior []string) {
+	for fromName, fromTask := range taskList {
+		if fromName == task.TaskName || !fromTask.hasOutputs() {
+			continue
+		}
+		if task.readsFrom(fromTask) {
+			prior = append(prior, fromName)
+		}
+	}
+	sort.Strings(prior)
+	return
+}
+
+func (task *Task) hasOutputs() bool {
+	return len(task.TaskOutputs) > 0
+}
+
+func (task *Task) hasInputs() bool {
+	return len(task.TaskInputs) > 0
+}
+
+// any input pattern of any job matches any output of fromTask
+func (task *Task) readsFrom(fromTask *Task) bool {
+	for _, inputs := range task.Inputs {
+		for _, input := range inputs {
+			for _, outputs := range fromTask.Outputs {
+				for _, output := range outputs {
+					if ok, _ := filepath.Match(input, output); ok || input == output {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// linkPrior return prior list of every task:
+// explicit prior column is honoured, inferred prior is used for task without prior column,
+// and mismatch between explicit and inferred prior is logged as warning
+func linkPrior(taskList map[string]*Task) map[string][]string {
+	var priorMap = make(map[string][]string)
+	for taskName, task := range taskList {
+		var explicit = splitList(task.TaskInfo["prior"])
+		for _, from := range explicit {
+			if _, ok := taskList[from]; !ok {
+				log.Fatalf("can not find prior[%s] of Task[%s]", from, taskName)
+			}
+		}
+		var inferred []string
+		if task.hasInputs() {
+			inferred = inferPrior(task, taskList)
+		}
+		if len(explicit) == 0 {
+			if len(inferred) > 0 {
+				log.Printf("Task[%s] infer prior:%s", taskName, strings.Join(inferred, ","))
+			}
+			priorMap[taskName] = inferred
+			continue
+		}
+		priorMap[taskName] = explicit
+		if !task.hasInputs() {
+			continue
+		}
+		var explicitMap = make(map[string]bool)
+		for _, from := range explicit {
+			explicitMap[from] = true
+			if taskList[from].hasOutputs() && !contains(inferred, from) && !crossScope(taskList[from], task) {
+				log.Printf("Warning: Task[%s] prior[%s] not inferred from inputs/outputs", taskName, from)
+			}
+		}
+		for _, from := range inferred {
+			if !explicitMap[from] {
+				log.Printf("Warning: Task[%s] reads outputs of Task[%s], which is not in prior", taskName, from)
+			}
+		}
+	}
+	checkCycle(priorMap)
+	return priorMap
+}
+
+// crossScope is true for prior of barcode step to sample step, e.g. split to sample steps reading raw fastq:
+// outputs of barcode job can not name files in dirs of its samples, so inference can not find the edge
+func crossScope(fromTask, task *Task) bool {
+	return fromTask.TaskType == "barcode" && task.TaskType == "sample"
+}
+
+func checkCycle(priorMap map[string][]string) {
+	if cycle := findCycle(priorMap); cycle != nil {
+		log.Fatalf("prior cycle:%s", strings.Join(cycle, "->"))
+	}
+}
+
+// findCycle return first cycle of prior in order of task name as path from a task back to it, nil if none
+func findCycle(priorMap map[string][]string) (cycle []string) {
+	// 0:unvisited 1:visiting 2:done
+	var state = make(map[string]int)
+	var visit func(name string, path []string)
+	visit = func(name string, path []string) {
+		switch state[name] {
+		case 1:
+			for i := range path {
+				if path[i] == name {
+					cycle = append(append([]string{}, path[i:]...), name)
+					return
+				}
+			}
+		case 2:
+			return
+		}
+		state[name] = 1
+		for _, from := range priorMap[name] {
+			if visit(from, append(path, name)); cycle != nil {
+				return
+			}
+		}
+		state[name] = 2
+	}
+	var names []string
+	for name := range priorMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if visit(name, nil); cycle != nil {
+			return
+		}
+	}
+	return
+}
+
+func contains(list []string, str string) bool {
+	for _, s := range list {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
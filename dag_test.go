@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFindCycle(t *testing.T) {
+	var tests = []struct {
+		priorMap map[string][]string
+		cycle    string
+	}{
+		{map[string][]string{"A": nil, "B": {"A"}, "C": {"A", "B"}}, ""},
+		{map[string][]string{"A": {"A"}}, "A->A"},
+		{map[string][]string{"A": {"C"}, "B": {"A"}, "C": {"B"}}, "A->C->B->A"},
+		// cycle not through first task
+		{map[string][]string{"A": {"B"}, "B": {"C"}, "C": {"D"}, "D": {"C"}}, "C->D->C"},
+		// diamond is not cycle
+		{map[string][]string{"A": nil, "B": {"A"}, "C": {"A"}, "D": {"B", "C"}}, ""},
+	}
+	for _, test := range tests {
+		if cycle := strings.Join(findCycle(test.priorMap), "->"); cycle != test.cycle {
+			t.Errorf("findCycle(%v)=%s want %s", test.priorMap, cycle, test.cycle)
+		}
+	}
+}
+
+func TestLinkPrior(t *testing.T) {
+	var taskList = map[string]*Task{
+		"bwa":  {TaskName: "bwa", TaskInfo: map[string]string{}, TaskOutputs: []string{"x"}, Outputs: map[string][]string{"S1": {"/out/S1/bwa/S1.bam"}}},
+		"gatk": {TaskName: "gatk", TaskInfo: map[string]string{}, TaskInputs: []string{"x"}, Inputs: map[string][]string{"S1": {"/out/S1/bwa/*.bam"}}},
+		"qc":   {TaskName: "qc", TaskInfo: map[string]string{"prior": "bwa"}},
+		// explicit prior not inferred from inputs
+		"split":   {TaskName: "split", TaskType: "barcode", TaskInfo: map[string]string{}, TaskOutputs: []string{"x"}, Outputs: map[string][]string{"B1": {"/out/barcode/B1.stat"}}},
+		"fastqQC": {TaskName: "fastqQC", TaskType: "sample", TaskInfo: map[string]string{"prior": "split"}, TaskOutputs: []string{"x"}, Outputs: map[string][]string{"S1": {"/out/S1/filter/S1.fq.gz"}}, TaskInputs: []string{"x"}, Inputs: map[string][]string{"S1": {"/out/S1/raw/S1.fq.gz"}}},
+		"depth":   {TaskName: "depth", TaskType: "sample", TaskInfo: map[string]string{"prior": "fastqQC"}, TaskInputs: []string{"x"}, Inputs: map[string][]string{"S1": {"/out/S1/bwa/S1.bam"}}},
+	}
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	var priorMap = linkPrior(taskList)
+	for name, prior := range map[string]string{"bwa": "", "gatk": "bwa", "qc": "bwa", "fastqQC": "split", "depth": "fastqQC"} {
+		if got := strings.Join(priorMap[name], ","); got != prior {
+			t.Errorf("prior of %s:%s want %s", name, got, prior)
+		}
+	}
+	// barcode to sample prior can not be inferred, no warning
+	if strings.Contains(logs.String(), "prior[split]") {
+		t.Errorf("warning of barcode to sample prior:%s", logs.String())
+	}
+	if !strings.Contains(logs.String(), "Task[depth] prior[fastqQC] not inferred") {
+		t.Errorf("no warning of prior not inferred:%s", logs.String())
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const gb = 1 << 30
+
+// error of statFree on platform without statfs
+var errNoStatfs = errors.New("free space not supported on " + runtime.GOOS)
+
+// interval to check free space while scheduling is paused
+var diskCheckInterval = time.Minute
+
+// freeSpace of filesystem holding path, nearest existing parent is used for path not created yet
+func freeSpace(path string) (free float64, err error) {
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	for {
+		if _, err = os.Stat(path); err == nil || filepath.Dir(path) == path {
+			break
+		}
+		path = filepath.Dir(path)
+	}
+	return statFree(path)
+}
+
+// estimateDisk return size of input fastq and space written by steps, as fastq size * sum of disk of steps
+func estimateDisk(rows []InputRow, steps []StepConfig) (fqSize, need float64) {
+	fqSize = jobFqSize(rows)["batch"]["batch"]
+	for _, step := range steps {
+		need += fqSize * step.Disk
+	}
+	return
+}
+
+// jobFqSize return size of input fastq of jobs by task type and job name:
+// sample -> sampleID, barcode -> barcode, and batch -> batch of all
+func jobFqSize(rows []InputRow) map[string]map[string]float64 {
+	var size = map[string]map[string]float64{"sample": {}, "barcode": {}, "batch": {}}
+	var seen = make(map[[3]string]bool)
+	for _, row := range rows {
+		for _, column := range []string{"fq1", "fq2"} {
+			var path = row.Item[column]
+			if path == "" {
+				continue
+			}
+			var fileInfo, err = os.Stat(path)
+			if err != nil {
+				continue
+			}
+			for taskType, jobName := range map[string]string{"sample": row.Item["sampleID"], "barcode": row.Item["barcode"], "batch": "batch"} {
+				var key = [3]string{taskType, jobName, path}
+				if jobName != "" && !seen[key] {
+					seen[key] = true
+					size[taskType][jobName] += float64(fileInfo.Size())
+				}
+			}
+		}
+	}
+	return size
+}
+
+// CheckDisk refuse to start when free space of outdir is less than estimated need and -minFree,
+// skipped with warning where free space is not available
+func CheckDisk(rows []InputRow, steps []StepConfig) error {
+	var fqSize, need = estimateDisk(rows, steps)
+	var free, err = freeSpace(*outDir)
+	if err == errNoStatfs {
+		log.Printf("Warning: skip disk check:%v", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("disk: input fastq %.1fG, estimated need %.1fG, free %.1fG", fqSize/gb, need/gb, free/gb)
+	if free < need+*minFree*gb {
+		return fmt.Errorf("not enough disk space of %s: need %.1fG + minFree %.1fG, free %.1fG", *outDir, need/gb, *minFree, free/gb)
+	}
+	return nil
+}
+
+// DiskWatchdog pause scheduling new jobs while free space of path is less than minFree plus estimate of the job,
+// estimate is input fastq size of the job * disk of its step
+type DiskWatchdog struct {
+	path    string
+	minFree float64
+	// task type -> job name -> input fastq size
+	fqSize map[string]map[string]float64
+	lock   sync.Mutex
+}
+
+// watchdog of current run, nil if not used
+var diskWatchdog *DiskWatchdog
+
+// need return estimated space written by job of task type, whose step writes disk times of its input
+func (watchdog *DiskWatchdog) need(taskType, jobName string, disk float64) float64 {
+	return watchdog.fqSize[taskType][jobName] * disk
+}
+
+// wait until free space is more than minFree plus need, jobs waiting together are released together
+func (watchdog *DiskWatchdog) wait(need float64) {
+	watchdog.lock.Lock()
+	defer watchdog.lock.Unlock()
+	var paused bool
+	for {
+		var free, err = freeSpace(watchdog.path)
+		if err == errNoStatfs {
+			// warned by CheckDisk
+			return
+		}
+		if err != nil {
+			log.Printf("Warning: can not get free space of %s:%v", watchdog.path, err)
+			return
+		}
+		if free >= watchdog.minFree+need {
+			if paused {
+				log.Printf("disk: free %.1fG, resume scheduling", free/gb)
+			}
+			return
+		}
+		if !paused {
+			log.Printf("disk: free %.1fG less than minFree %.1fG + job estimate %.1fG, pause scheduling new jobs", free/gb, watchdog.minFree/gb, need/gb)
+			paused = true
+		}
+		time.Sleep(diskCheckInterval)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package main
+
+// statFree is not supported, disk check is skipped and watchdog is not paused
+func statFree(path string) (float64, error) {
+	return 0, errNoStatfs
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJobFqSize(t *testing.T) {
+	var dir = t.TempDir()
+	var fq = func(name string, size int) string {
+		var path = filepath.Join(dir, name)
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	var l1, l2 = fq("L1_1.fq.gz", 100), fq("L1_2.fq.gz", 100)
+	var s3 = fq("S3_1.fq.gz", 10)
+	var rows = []InputRow{
+		// samples of barcode B1 share fastq of the barcode
+		{2, map[string]string{"sampleID": "S1", "barcode": "B1", "fq1": l1, "fq2": l2}},
+		{3, map[string]string{"sampleID": "S2", "barcode": "B1", "fq1": l1, "fq2": l2}},
+		{4, map[string]string{"sampleID": "S3", "fq1": s3, "fq2": filepath.Join(dir, "missing")}},
+	}
+	var size = jobFqSize(rows)
+	for _, test := range []struct {
+		taskType, jobName string
+		size              float64
+	}{
+		{"sample", "S1", 200},
+		{"sample", "S3", 10},
+		{"barcode", "B1", 200},
+		{"batch", "batch", 210},
+	} {
+		if got := size[test.taskType][test.jobName]; got != test.size {
+			t.Errorf("fastq size of %s %s:%v want %v", test.taskType, test.jobName, got, test.size)
+		}
+	}
+	var watchdog = &DiskWatchdog{path: dir, fqSize: size}
+	if need := watchdog.need("sample", "S1", 1.5); need != 300 {
+		t.Errorf("need:%v want 300", need)
+	}
+	// enough space returns at once
+	watchdog.wait(watchdog.need("sample", "S3", 1))
+}
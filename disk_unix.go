@@ -0,0 +1,14 @@
+//go:build linux || darwin
+
+package main
+
+import "syscall"
+
+// statFree return free space available to user of filesystem holding existing path
+func statFree(path string) (float64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return float64(stat.Bavail) * float64(stat.Bsize), nil
+}
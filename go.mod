@@ -1,11 +1,21 @@
 module github.com/liserjrqlxue/DrugPipeline
 
-go 1.13
+go 1.22
 
 require (
-	github.com/360EntSecGroup-Skylar/excelize/v2 v2.1.0 // indirect
+	github.com/360EntSecGroup-Skylar/excelize/v2 v2.1.0
+	github.com/klauspost/compress v1.18.0
+	github.com/klauspost/pgzip v1.2.6
 	github.com/liserjrqlxue/goUtil v0.0.15
 	github.com/liserjrqlxue/libIM v0.0.0-20200427063017-a5926004040e
 	github.com/liserjrqlxue/simple-util v1.0.1
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/dustin/go-humanize v1.0.0 // indirect
+	github.com/liserjrqlxue/crypto v0.0.0-20181031021947-e63cf1f4c48a // indirect
+	github.com/mohae/deepcopy v0.0.0-20170929034955-c48cc78d4826 // indirect
 	golang.org/x/net v0.0.0-20200425230154-ff2c4b7c35a0 // indirect
+	golang.org/x/text v0.3.2 // indirect
 )
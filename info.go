@@ -10,6 +10,7 @@ type Barcode struct {
 	list    string
 	fq1     string
 	fq2     string
+	lanes   []*Lane
 	samples map[string]*Sample
 }
 
@@ -18,5 +19,14 @@ type Sample struct {
 	sampleNum string
 	barcode   string
 	primer    string
+	laneList  string
+	lanes     []*Lane
 	info      map[string]string
 }
+
+// Lane is one fastq pair of a sample or barcode, lane is empty without lane column
+type Lane struct {
+	lane string
+	fq1  string
+	fq2  string
+}
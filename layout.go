@@ -0,0 +1,167 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Layout is output directory layout, dir name -> path relative to outdir (batch) or outdir/sampleID (sample)
+type Layout struct {
+	Batch  map[string]string `yaml:"batch,omitempty" json:"batch,omitempty"`
+	Sample map[string]string `yaml:"sample,omitempty" json:"sample,omitempty"`
+	// file and line of sections and dirs set by pipeline file, key as batch or batch.NAME
+	pos map[string]filePos
+}
+
+// filePos is position in pipeline file, for errors
+type filePos struct {
+	file string
+	line int
+}
+
+// default layout, used by tsv pipeline and sections not set by yaml/json pipeline
+var defaultLayout = Layout{
+	Batch: map[string]string{
+		"barcode": "barcode",
+		"shell":   "shell",
+		"javatmp": "javatmp",
+	},
+	Sample: map[string]string{
+		"raw":    "raw",
+		"filter": "filter",
+		"bwa":    "bwa",
+		"shell":  "shell",
+		"vcf":    "vcf",
+	},
+}
+
+// dirs used by pipeline itself
+var (
+	requiredBatchDirs  = []string{"barcode", "shell"}
+	requiredSampleDirs = []string{"raw", "shell"}
+)
+
+// layout of current run, set by main from pipeline
+var layout = defaultLayout
+
+// dir name is exported to scripts as dir_NAME
+var dirName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// merge newLayout into layout, each section set by newLayout replace the whole section
+func (layout Layout) merge(newLayout *Layout) Layout {
+	if newLayout == nil {
+		return layout
+	}
+	var pos = make(map[string]filePos)
+	for _, section := range []struct {
+		name string
+		dirs *map[string]string
+		set  map[string]string
+	}{
+		{"batch", &layout.Batch, newLayout.Batch},
+		{"sample", &layout.Sample, newLayout.Sample},
+	} {
+		var from = layout.pos
+		if section.set != nil {
+			*section.dirs = section.set
+			from = newLayout.pos
+		}
+		for key, p := range from {
+			if key == section.name || strings.HasPrefix(key, section.name+".") {
+				pos[key] = p
+			}
+		}
+	}
+	layout.pos = pos
+	return layout
+}
+
+// at return position of key of layout, fileName line 0 for default
+func (layout Layout) at(fileName, key string) filePos {
+	if p, ok := layout.pos[key]; ok {
+		return p
+	}
+	return filePos{fileName, 0}
+}
+
+func (layout Layout) isDefault() bool {
+	return sameDirs(layout.Batch, defaultLayout.Batch) && sameDirs(layout.Sample, defaultLayout.Sample)
+}
+
+func sameDirs(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, path := range a {
+		if b[name] != path {
+			return false
+		}
+	}
+	return true
+}
+
+// validate names and paths of layout, and dirs used by pipeline itself
+func (layout Layout) validate(fileName string, cfgErr *ConfigError) {
+	for _, section := range []struct {
+		name     string
+		dirs     map[string]string
+		required []string
+	}{
+		{"batch", layout.Batch, requiredBatchDirs},
+		{"sample", layout.Sample, requiredSampleDirs},
+	} {
+		for _, name := range section.required {
+			if section.dirs[name] == "" {
+				var p = layout.at(fileName, section.name)
+				cfgErr.addAt(p.file, p.line, "layout.%s missing %s", section.name, name)
+			}
+		}
+		for _, name := range sortedKeys(section.dirs) {
+			var path = section.dirs[name]
+			var p = layout.at(fileName, section.name+"."+name)
+			if !dirName.MatchString(name) {
+				cfgErr.addAt(p.file, p.line, "layout.%s name should be a shell variable name:%q", section.name, name)
+			}
+			if path == "" || filepath.IsAbs(path) || filepath.Clean(path) == "." || strings.HasPrefix(filepath.Clean(path), "..") {
+				cfgErr.addAt(p.file, p.line, "layout.%s.%s should be a relative path inside outdir:%q", section.name, name, path)
+			}
+		}
+	}
+}
+
+// batchDir return outdir/PATH of batch dir name
+func (layout Layout) batchDir(name string) string {
+	return filepath.Join(*outDir, layout.Batch[name])
+}
+
+// sampleDir return outdir/sampleID/PATH of sample dir name
+func (layout Layout) sampleDir(sampleID, name string) string {
+	return filepath.Join(*outDir, sampleID, layout.Sample[name])
+}
+
+// env of job as sorted dir_NAME=PATH, sample dirs only for sample job and override batch dirs of same name
+func (layout Layout) env(sampleID string) (env []string) {
+	var dirs = make(map[string]string)
+	for name := range layout.Batch {
+		dirs[name] = layout.batchDir(name)
+	}
+	if sampleID != "" {
+		for name := range layout.Sample {
+			dirs[name] = layout.sampleDir(sampleID, name)
+		}
+	}
+	for _, name := range sortedKeys(dirs) {
+		env = append(env, "dir_"+name+"="+dirs[name])
+	}
+	return
+}
+
+func sortedKeys(m map[string]string) (keys []string) {
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return
+}
@@ -5,15 +5,12 @@ import (
 	"github.com/liserjrqlxue/goUtil/jsonUtil"
 	"github.com/liserjrqlxue/goUtil/osUtil"
 	"github.com/liserjrqlxue/goUtil/simpleUtil"
-	"github.com/liserjrqlxue/goUtil/textUtil"
-	"github.com/liserjrqlxue/libIM"
 	simple_util "github.com/liserjrqlxue/simple-util"
 
 	"log"
 	"os"
 	"path/filepath"
 	"regexp"
-	"strings"
 )
 
 // os
@@ -37,7 +34,17 @@ var (
 	input = flag.String(
 		"input",
 		"",
-		"input list",
+		"input list, or Illumina SampleSheet.csv and xlsx sample sheet",
+	)
+	columns = flag.String(
+		"columns",
+		"",
+		"map columns of -input to input list, e.g. sampleID=Sample_ID,primer=index\nSampleSheet.csv and xlsx default map Sample_ID,index,Sample_Project,Lane to sampleID,primer,barcode,lane",
+	)
+	sheet = flag.String(
+		"sheet",
+		"",
+		"sheet name of xlsx -input, default first sheet",
 	)
 	outDir = flag.String(
 		"outdir",
@@ -94,26 +101,63 @@ var (
 		false,
 		"dry run for local",
 	)
+	minFree = flag.Float64(
+		"minFree",
+		0,
+		"min free space of outdir in GB, required above estimate of run before start, and above estimate of a job before it starts in local mode",
+	)
+	skipDiskCheck = flag.Bool(
+		"skipDiskCheck",
+		false,
+		"skip disk space check before start",
+	)
+	keepTemp = flag.Bool(
+		"keepTemp",
+		false,
+		"keep temp outputs of steps, which are deleted once every job reading them succeeded in local mode",
+	)
+	scratch = flag.String(
+		"scratch",
+		"",
+		"base dir of job scratch dirs for scratch steps, default $TMPDIR or /tmp of the node",
+	)
+	manifest = flag.Bool(
+		"manifest",
+		false,
+		"only write manifest and delivery of a finished run and exit, for sge mode",
+	)
+	deliverDir = flag.String(
+		"deliver",
+		"",
+		"delivery dir, deliverables of steps are hard linked or copied to it",
+	)
+	deliverName = flag.String(
+		"deliverName",
+		"{job}/{name}",
+		"path of deliverable in delivery dir, placeholders:{job} {sampleID} {step} {name} {base} {ext}",
+	)
+	deliverMode = flag.String(
+		"deliverMode",
+		"link",
+		"delivery mode:[link|copy], link fall back to copy across filesystems",
+	)
 	lane = flag.String(
 		"lane",
 		"",
 		"lane info",
 	)
+	profile = flag.String(
+		"profile",
+		"",
+		"named pipeline profile in local/etc, e.g. wes -> etc/wes.yaml, override -cfg",
+	)
+	convert = flag.String(
+		"convert",
+		"",
+		"convert -cfg to this file and exit, format by extension:[.tsv|.yaml|.json]",
+	)
 )
 
-var batchDirList = []string{
-	"shell",
-	"javatmp",
-}
-
-var sampleDirList = []string{
-	"raw",
-	"filter",
-	"bwa",
-	"shell",
-	"vcf",
-}
-
 var (
 	sep = regexp.MustCompile(`\s+`)
 )
@@ -122,14 +166,17 @@ func main() {
 	logVersion()
 	log.Println("args:", os.Args)
 	flag.Parse()
-	if *input == "" || *outDir == "" {
+	if *convert != "" {
+		var steps, dirLayout = loadSteps()
+		simpleUtil.CheckErr(ConvertPipeline(steps, dirLayout, *convert))
+		log.Printf("convert pipeline -> %s", *convert)
+		return
+	}
+	if (*input == "" && !*manifest) || *outDir == "" {
 		flag.Usage()
 		log.Printf("-input and -outdir required")
 		os.Exit(0)
 	}
-	if *lane != "" {
-		libIM.LaneInput = *lane
-	}
 
 	log.SetFlags(log.Ldate | log.Ltime)
 	if *logFile != "" {
@@ -152,41 +199,74 @@ func main() {
 		submitArgs = append(submitArgs, "-P", *proj)
 	}
 
-	info := parseInput(*input, *outDir)
-	createDir(*outDir, batchDirList, sampleDirList, info)
-	simpleUtil.CheckErr(simple_util.CopyFile(filepath.Join(*outDir, "input.list"), *input))
+	if *deliverMode != "link" && *deliverMode != "copy" {
+		log.Fatalf("-deliverMode should be link or copy:%q", *deliverMode)
+	}
+
+	var steps []StepConfig
+	steps, layout = loadSteps()
+	if *manifest {
+		manifestOfRun(steps, submitArgs)
+		return
+	}
+
+	// validate input list before any directory created
+	title, rows, err := ValidateInput(*input, steps)
+	simpleUtil.CheckErr(err)
+	if *mode != "im" && !*skipDiskCheck {
+		if err = CheckDisk(rows, steps); err != nil {
+			// jobs of sge write on other hosts, estimate of submit host is only a hint
+			if *mode != "local" {
+				log.Printf("Warning: %v", err)
+			} else {
+				log.Fatal(err)
+			}
+		}
+	}
+	info := parseInput(rows, *outDir)
+	createDir(layout, info)
+	writeBarcodeList(title, rows, info)
+	writeLaneList(info, hasBarcodeStep(steps))
+	var inputList = filepath.Join(*outDir, "input.list")
+	if isSheet(*input) || *columns != "" {
+		writeInputList(inputList, title, rows, nil)
+	} else {
+		simpleUtil.CheckErr(simple_util.CopyFile(inputList, *input))
+	}
 	// create outDir/step2.sh and write args to it
 	simple_util.Array2File(filepath.Join(*outDir, "run.sh"), " ", os.Args)
 
-	var infoMap = ParseInfoIM(*input)
-	var allSteps = ParseStepCfg(*cfg, infoMap)
+	var taskList = createTaskList(steps, submitArgs)
+	// create scripts after all tasks loaded, args may refer to other steps
+	for _, task := range taskList {
+		task.CreateScripts(info, taskList)
+	}
+	var priorMap = linkPrior(taskList)
+
+	// same tasks and scripts for im mode
+	var allSteps = CreateIMSteps(steps, taskList, priorMap)
 	simpleUtil.CheckErr(jsonUtil.Json2File(filepath.Join(*outDir, "allSteps.json"), allSteps))
 	if *mode == "im" {
 		return
 	}
 
-	// create taskList
-	cfgInfo, _ := textUtil.File2MapArray(*cfg, "\t", nil)
-	var taskList = make(map[string]*Task)
-
-	for _, item := range cfgInfo {
-		task := createTask(item, *localpath, submitArgs)
-		_, ok := taskList[task.TaskName]
-		if ok {
-			log.Fatal("dup TaskName:", task.TaskName)
-		}
-		taskList[task.TaskName] = task
-		// create scripts
-		task.CreateScripts(info)
+	runState, err = LoadRunState(filepath.Join(*outDir, "run.state.json"))
+	simpleUtil.CheckErr(err)
+	if *mode == "local" && !*dryRun && !*keepTemp {
+		tempCleaner = NewTempCleaner(taskList, runState)
+	}
+	if *mode == "local" && !*dryRun && !*skipDiskCheck {
+		diskWatchdog = &DiskWatchdog{path: *outDir, minFree: *minFree * gb, fqSize: jobFqSize(rows)}
 	}
+
 	var startTask = createStartTask()
 	var endTask = createEndTask()
 	// add prior to current TaskFrom and add current task to prior's TaskToChan
 	// set startTask as prior of first tasks
 	for taskName, item := range taskList {
-		prior := item.TaskInfo["prior"]
-		if prior != "" {
-			for _, from := range strings.Split(prior, ",") {
+		prior := priorMap[taskName]
+		if len(prior) > 0 {
+			for _, from := range prior {
 				fromTask := taskList[from]
 				item.TaskFrom = append(item.TaskFrom, fromTask)
 				fromTask.End = false
@@ -260,5 +340,68 @@ func main() {
 	for i := 0; i < *threshold; i++ {
 		throttle <- true
 	}
+	if *mode == "local" && !*dryRun {
+		writeManifest(steps, taskList, info)
+	}
 	log.Printf("All Done!")
 }
+
+// write outdir/manifest.tsv of deliverables and deliver them if -deliver set
+func writeManifest(steps []StepConfig, taskList map[string]*Task, info Info) {
+	var items, err = CreateManifest(steps, taskList, info)
+	simpleUtil.CheckErr(err)
+	if len(items) == 0 {
+		return
+	}
+	if *deliverDir != "" {
+		simpleUtil.CheckErr(Deliver(items, *deliverDir, *deliverName, *deliverMode))
+	}
+	var fileName = filepath.Join(*outDir, "manifest.tsv")
+	simpleUtil.CheckErr(WriteManifest(fileName, items))
+	log.Printf("write manifest of %d deliverables:%s", len(items), fileName)
+}
+
+// manifestOfRun write manifest of finished run from outdir/input.list,
+// jobs are resolved again but no script, list or dir of run is written, and raw input is not needed
+func manifestOfRun(steps []StepConfig, submitArgs []string) {
+	var inputList = filepath.Join(*outDir, "input.list")
+	var inputErr = &InputError{File: inputList}
+	var _, rows = readInputTsv(inputList, inputErr)
+	if len(inputErr.Problems) > 0 {
+		log.Fatal(inputErr)
+	}
+	var info = parseInput(rows, *outDir)
+	var taskList = createTaskList(steps, submitArgs)
+	for _, task := range taskList {
+		task.ResolveJobs(info, taskList)
+	}
+	writeManifest(steps, taskList, info)
+}
+
+// createTaskList create task of each step
+func createTaskList(steps []StepConfig, submitArgs []string) map[string]*Task {
+	var taskList = make(map[string]*Task)
+	for _, item := range steps {
+		task := createTask(item, *localpath, submitArgs)
+		_, ok := taskList[task.TaskName]
+		if ok {
+			log.Fatal("dup TaskName:", task.TaskName)
+		}
+		taskList[task.TaskName] = task
+	}
+	return taskList
+}
+
+// load pipeline and layout from -profile or -cfg
+func loadSteps() ([]StepConfig, Layout) {
+	var steps []StepConfig
+	var dirLayout Layout
+	var err error
+	if *profile != "" {
+		steps, dirLayout, err = LoadProfile(*profile, filepath.Join(*localpath, "etc"))
+	} else {
+		steps, dirLayout, err = LoadPipeline(*cfg)
+	}
+	simpleUtil.CheckErr(err)
+	return steps, dirLayout
+}
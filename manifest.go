@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/liserjrqlxue/goUtil/osUtil"
+	"github.com/liserjrqlxue/goUtil/simpleUtil"
+	simple_util "github.com/liserjrqlxue/simple-util"
+)
+
+// columns of manifest.tsv
+var manifestColumns = []string{"job", "sampleID", "step", "path", "size", "md5", "sha256", "delivered"}
+
+// ManifestItem is one deliverable of a job
+type ManifestItem struct {
+	Job       string
+	SampleID  string // sample of sample job, or sample whose dir holds deliverable of batch or barcode job
+	Step      string
+	Path      string
+	Size      int64
+	MD5       string
+	SHA256    string
+	Delivered string
+}
+
+// CreateManifest checksum deliverables of steps, in step order and sorted job, and write PATH.md5 beside each,
+// deliverables of skipped jobs are excluded
+func CreateManifest(steps []StepConfig, taskList map[string]*Task, info Info) (items []ManifestItem, err error) {
+	for _, step := range steps {
+		var task = taskList[step.Name]
+		var jobs []string
+		for jobName := range task.Deliver {
+			jobs = append(jobs, jobName)
+		}
+		sort.Strings(jobs)
+		for _, jobName := range jobs {
+			if task.Skip[jobName] {
+				continue
+			}
+			for _, path := range task.Deliver[jobName] {
+				var item = ManifestItem{Job: jobName, SampleID: jobName, Step: step.Name, Path: path}
+				if task.TaskType != "sample" {
+					item.SampleID = sampleOfPath(path, info)
+				}
+				item.Size, item.MD5, item.SHA256, err = checksum(path)
+				if err != nil {
+					return nil, fmt.Errorf("deliverable of Task[%s:%s]:%v", step.Name, jobName, err)
+				}
+				if err = writeMd5(path, item.MD5); err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			}
+		}
+	}
+	return
+}
+
+// sampleOfPath return sample whose dir outdir/sampleID holds path, empty if none
+func sampleOfPath(path string, info Info) string {
+	var rel, err = filepath.Rel(*outDir, path)
+	if err != nil {
+		return ""
+	}
+	var sampleID = strings.Split(filepath.ToSlash(rel), "/")[0]
+	if _, ok := info.SampleMap[sampleID]; ok {
+		return sampleID
+	}
+	return ""
+}
+
+// checksum return size, md5 and sha256 of file in one read
+func checksum(path string) (size int64, md5sum, sha256sum string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer simpleUtil.DeferClose(file)
+	var md5Hash, sha256Hash = md5.New(), sha256.New()
+	size, err = io.Copy(io.MultiWriter(md5Hash, sha256Hash), file)
+	if err != nil {
+		return
+	}
+	return size, hex.EncodeToString(md5Hash.Sum(nil)), hex.EncodeToString(sha256Hash.Sum(nil)), nil
+}
+
+// writeMd5 write PATH.md5 in md5sum format, checked by md5sum -c in dir of PATH,
+// old file is removed first as it may be a hard link
+func writeMd5(path, md5sum string) error {
+	if err := os.Remove(path + ".md5"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.WriteFile(path+".md5", []byte(md5sum+"  "+filepath.Base(path)+"\n"), 0644)
+}
+
+// Deliver hard link or copy deliverables and their md5 to dir, renamed by template,
+// placeholders of template: {job} {sampleID} {step} {name} {base} {ext}
+func Deliver(items []ManifestItem, dir, template, mode string) error {
+	var seen = make(map[string]string)
+	for i := range items {
+		var item = &items[i]
+		if item.SampleID == "" && strings.Contains(template, "{sampleID}") {
+			return fmt.Errorf("deliverable %s of Task[%s:%s] has no sample for {sampleID} of -deliverName", item.Path, item.Step, item.Job)
+		}
+		var target = filepath.Join(dir, deliverPath(template, *item))
+		if other, ok := seen[target]; ok {
+			return fmt.Errorf("deliver %s and %s to same %s, check -deliverName", other, item.Path, target)
+		}
+		seen[target] = item.Path
+		if err := deliverFile(item.Path, target, mode); err != nil {
+			return err
+		}
+		// md5 file refers to base name of deliverable
+		if err := writeMd5(target, item.MD5); err != nil {
+			return err
+		}
+		item.Delivered = target
+	}
+	return nil
+}
+
+func deliverPath(template string, item ManifestItem) string {
+	var name = filepath.Base(item.Path)
+	var ext = filepath.Ext(name)
+	return strings.NewReplacer(
+		"{job}", item.Job,
+		"{sampleID}", item.SampleID,
+		"{step}", item.Step,
+		"{name}", name,
+		"{base}", strings.TrimSuffix(name, ext),
+		"{ext}", ext,
+	).Replace(template)
+}
+
+// deliverFile replace target by hard link or copy of path, copy if hard link fails
+func deliverFile(path, target, mode string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if mode == "link" {
+		var err = os.Link(path, target)
+		if err == nil {
+			return nil
+		}
+		log.Printf("Warning: hard link failed, copy instead:%v", err)
+	}
+	return simple_util.CopyFile(target, path)
+}
+
+// WriteManifest write items as tsv
+func WriteManifest(fileName string, items []ManifestItem) error {
+	var file = osUtil.Create(fileName)
+	defer simpleUtil.DeferClose(file)
+	var w = bufio.NewWriter(file)
+	fmt.Fprintln(w, strings.Join(manifestColumns, "\t"))
+	for _, item := range items {
+		fmt.Fprintln(w, strings.Join([]string{item.Job, item.SampleID, item.Step, item.Path, strconv.FormatInt(item.Size, 10), item.MD5, item.SHA256, item.Delivered}, "\t"))
+	}
+	return w.Flush()
+}
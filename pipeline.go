@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -10,34 +11,67 @@ import (
 	"github.com/liserjrqlxue/goUtil/simpleUtil"
 )
 
-func createShell(fileName, script string, args ...string) {
+func createShell(fileName, script string, env, outputs []string, args ...string) {
 	var file = osUtil.Create(fileName)
 	defer simpleUtil.DeferClose(file)
 
-	fmtUtil.Fprintf(file, "#!/bin/bash\n#$ -e %s\n#$ -o %s\nsh %s %s\n", filepath.Dir(fileName), filepath.Dir(fileName), script, strings.Join(args, " "))
+	fmtUtil.Fprintf(file, "#!/bin/bash\n#$ -e %s\n#$ -o %s\n", filepath.Dir(fileName), filepath.Dir(fileName))
+	for _, kv := range env {
+		fmtUtil.Fprintf(file, "%s\n", exportEnv(kv))
+	}
+	fmtUtil.Fprintf(file, "sh %s %s\n", script, strings.Join(args, " "))
+	if len(outputs) > 0 {
+		// exit 100 put sge job in error state and hold its successors
+		fmtUtil.Fprintf(file, "status=$?\nif [ $status -ne 0 ];then exit $status;fi\n")
+		for _, output := range outputs {
+			fmtUtil.Fprintf(file, "if [ ! -s %s ];then echo missing output:%s >&2;exit 100;fi\n", shellQuote(output), shellQuote(output))
+		}
+	}
+}
+
+// exportEnv return export of KEY=value with value single quoted, so it is never split or expanded by shell
+func exportEnv(kv string) string {
+	var kvs = strings.SplitN(kv, "=", 2)
+	if len(kvs) == 1 {
+		return "export " + shellQuote(kvs[0])
+	}
+	return "export " + kvs[0] + "=" + shellQuote(kvs[1])
+}
+
+// shellQuote single quote s for shell, a quote in s closes, escapes and reopens the quoting
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
 
-func createDir(workDir string, batchDirList, sampleDirList []string, info Info) {
-	for _, subDir := range batchDirList {
+// every declared output must exist and be non-empty
+func verifyOutputs(outputs []string) error {
+	var missing []string
+	for _, output := range outputs {
+		var fileInfo, err = os.Stat(output)
+		if err != nil || fileInfo.Size() == 0 {
+			missing = append(missing, output)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing or empty outputs:%v", missing)
+	}
+	return nil
+}
+
+func createDir(dirLayout Layout, info Info) {
+	for name := range dirLayout.Batch {
 		simpleUtil.CheckErr(
 			os.MkdirAll(
-				filepath.Join(
-					workDir,
-					subDir,
-				),
+				dirLayout.batchDir(name),
 				0755,
 			),
 		)
 	}
 	for sampleID := range info.SampleMap {
-		for _, subDir := range sampleDirList {
+		for name := range dirLayout.Sample {
 			simpleUtil.CheckErr(
 				os.MkdirAll(
-					filepath.Join(
-						workDir,
-						sampleID,
-						subDir,
-					),
+					dirLayout.sampleDir(sampleID, name),
 					0755,
 				),
 			)
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	var tests = map[string]string{
+		"":       "''",
+		"a b":    "'a b'",
+		"$HOME":  "'$HOME'",
+		"it's":   `'it'\''s'`,
+		"*.bam;": "'*.bam;'",
+	}
+	for s, quoted := range tests {
+		if got := shellQuote(s); got != quoted {
+			t.Errorf("shellQuote(%q)=%s want %s", s, got, quoted)
+		}
+	}
+}
+
+func TestCreateShellEnv(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("no bash")
+	}
+	var dir = t.TempDir()
+	var value = "a  b;touch " + filepath.Join(dir, "pwned") + " $(id) * 'q'"
+	var script = filepath.Join(dir, "step.sh")
+	if err := os.WriteFile(script, []byte(`printf %s "$V" > $1/out`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var job = filepath.Join(dir, "job.sh")
+	createShell(job, script, []string{"V=" + value}, nil, dir)
+	if out, err := exec.Command("bash", job).CombinedOutput(); err != nil {
+		t.Fatalf("%v:%s", err, out)
+	}
+	var out, err = os.ReadFile(filepath.Join(dir, "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != value {
+		t.Errorf("V=%q want %q", out, value)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "pwned")); err == nil || !strings.Contains(err.Error(), "no such file") {
+		t.Errorf("env value executed by shell")
+	}
+}
+
+func TestCreateShellOutputs(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("no bash")
+	}
+	var dir = t.TempDir()
+	var output = filepath.Join(dir, "a b", "out*.txt")
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// a file matching the glob must not pass the check of output
+	if err := os.WriteFile(filepath.Join(dir, "a b", "out1.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var script = filepath.Join(dir, "step.sh")
+	if err := os.WriteFile(script, []byte("exit 0"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var job = filepath.Join(dir, "job.sh")
+	createShell(job, script, nil, []string{output})
+	var out, err = exec.Command("bash", job).CombinedOutput()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 100 || !strings.Contains(string(out), "missing output:"+output) {
+		t.Errorf("%v:%s", err, out)
+	}
+	if err = os.WriteFile(output, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err = exec.Command("bash", job).CombinedOutput(); err != nil {
+		t.Errorf("%v:%s", err, out)
+	}
+}
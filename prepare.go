@@ -1,23 +1,30 @@
 package main
 
 import (
-	simple_util "github.com/liserjrqlxue/simple-util"
+	"fmt"
 	"log"
 	"path/filepath"
+	"strings"
+
+	"github.com/liserjrqlxue/goUtil/fmtUtil"
+	"github.com/liserjrqlxue/goUtil/osUtil"
+	"github.com/liserjrqlxue/goUtil/simpleUtil"
 )
 
-func parseInput(input, outDir string) (info Info) {
+func parseInput(rows []InputRow, outDir string) (info Info) {
 	info = Info{
 		SampleMap:  make(map[string]*Sample),
 		BarcodeMap: make(map[string]*Barcode),
 	}
-	inputInfo, _ := simple_util.File2MapArray(input, "\t", nil)
-	for _, item := range inputInfo {
+	for _, row := range rows {
+		item := row.Item
 		sampleID := item["sampleID"]
 		barcode := item["barcode"]
-
-		fq1 := item["fq1"]
-		fq2 := item["fq2"]
+		lane := &Lane{
+			lane: item["lane"],
+			fq1:  item["fq1"],
+			fq2:  item["fq2"],
+		}
 		sampleNum := item["sampleNum"]
 		primer := item["primer"]
 
@@ -28,25 +35,109 @@ func parseInput(input, outDir string) (info Info) {
 				sampleNum: sampleNum,
 				barcode:   barcode,
 				primer:    primer,
+				laneList:  filepath.Join(outDir, sampleID, sampleID+".lane.list"),
 				info:      item,
 			}
 			info.SampleMap[sampleID] = sampleInfo
-		} else {
-			log.Fatal("dup sampleID:", sampleID)
+		} else if sampleInfo.hasLane(lane.lane) {
+			log.Fatalf("dup sampleID:%s lane:%s", sampleID, lane.lane)
 		}
+		sampleInfo.lanes = append(sampleInfo.lanes, lane)
 
 		barcodeInfo, ok := info.BarcodeMap[barcode]
 		if !ok {
 			barcodeInfo = &Barcode{
 				barcode: barcode,
-				list:    filepath.Join(outDir, "barcode", "barcode."+barcode+".list"),
-				fq1:     fq1,
-				fq2:     fq2,
+				list:    filepath.Join(layout.batchDir("barcode"), "barcode."+barcode+".list"),
+				fq1:     lane.fq1,
+				fq2:     lane.fq2,
 				samples: make(map[string]*Sample),
 			}
 			info.BarcodeMap[barcode] = barcodeInfo
 		}
+		if !barcodeInfo.hasLane(lane.lane) {
+			barcodeInfo.lanes = append(barcodeInfo.lanes, lane)
+		}
 		barcodeInfo.samples[sampleID] = sampleInfo
 	}
 	return
 }
+
+func (sample *Sample) hasLane(lane string) bool {
+	for _, l := range sample.lanes {
+		if l.lane == lane {
+			return true
+		}
+	}
+	return false
+}
+
+func (barcode *Barcode) hasLane(lane string) bool {
+	for _, l := range barcode.lanes {
+		if l.lane == lane {
+			return true
+		}
+	}
+	return false
+}
+
+// laneNames of sample, comma separated
+func (sample *Sample) laneNames() string {
+	var names []string
+	for _, l := range sample.lanes {
+		names = append(names, l.lane)
+	}
+	return strings.Join(names, ",")
+}
+
+// rawFq is output of splitBarcode: sample dir raw/sampleID[.lane].raw_[12].fq.gz
+func rawFq(sampleID, lane string, read int) string {
+	var name = sampleID
+	if lane != "" {
+		name += "." + lane
+	}
+	return filepath.Join(layout.sampleDir(sampleID, "raw"), fmt.Sprintf("%s.raw_%d.fq.gz", name, read))
+}
+
+// writeLaneList write lane, fq1 and fq2 of each sample to its lane list for sample steps,
+// fastq is output of splitBarcode if split, otherwise from input list
+func writeLaneList(info Info, split bool) {
+	for sampleID, sampleInfo := range info.SampleMap {
+		var file = osUtil.Create(sampleInfo.laneList)
+		fmtUtil.Fprintln(file, "lane\tfq1\tfq2")
+		for _, l := range sampleInfo.lanes {
+			var fq1, fq2 = l.fq1, l.fq2
+			if split {
+				fq1 = rawFq(sampleID, l.lane, 1)
+				fq2 = rawFq(sampleID, l.lane, 2)
+			}
+			fmtUtil.Fprintf(file, "%s\t%s\t%s\n", l.lane, fq1, fq2)
+		}
+		simpleUtil.CheckErr(file.Close())
+	}
+}
+
+// writeBarcodeList write input list title and rows of each barcode to its list for splitBarcode
+func writeBarcodeList(title []string, rows []InputRow, info Info) {
+	for barcode, barcodeInfo := range info.BarcodeMap {
+		writeInputList(barcodeInfo.list, title, rows, func(item map[string]string) bool { return item["barcode"] == barcode })
+		log.Printf("write barcode[%s] list:%s", barcode, barcodeInfo.list)
+	}
+}
+
+// writeInputList write title and rows passing filter as tab separated input list, nil filter for all rows
+func writeInputList(fileName string, title []string, rows []InputRow, filter func(map[string]string) bool) {
+	var file = osUtil.Create(fileName)
+	defer simpleUtil.DeferClose(file)
+	fmtUtil.Fprintln(file, strings.Join(title, "\t"))
+	for _, row := range rows {
+		if filter != nil && !filter(row.Item) {
+			continue
+		}
+		var array []string
+		for _, key := range title {
+			array = append(array, row.Item[key])
+		}
+		fmtUtil.Fprintln(file, strings.Join(array, "\t"))
+	}
+}
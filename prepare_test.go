@@ -0,0 +1,25 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestParseInputLayout(t *testing.T) {
+	*outDir = "/out"
+	defer func() { layout = defaultLayout }()
+	layout = Layout{
+		Batch:  map[string]string{"barcode": "split/lists", "shell": "shell"},
+		Sample: map[string]string{"raw": "fastq/raw", "shell": "shell"},
+	}
+	var rows = []InputRow{
+		{2, map[string]string{"sampleID": "S1", "barcode": "B1", "lane": "L1", "fq1": "/in/1.fq.gz", "fq2": "/in/2.fq.gz"}},
+	}
+	var info = parseInput(rows, *outDir)
+	if list := info.BarcodeMap["B1"].list; filepath.Dir(list) != layout.batchDir("barcode") || list != "/out/split/lists/barcode.B1.list" {
+		t.Errorf("barcode list:%s", list)
+	}
+	if fq := rawFq("S1", "L1", 2); fq != "/out/S1/fastq/raw/S1.L1.raw_2.fq.gz" {
+		t.Errorf("raw fastq:%s", fq)
+	}
+}
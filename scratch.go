@@ -0,0 +1,109 @@
+package main
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/liserjrqlxue/goUtil/fmtUtil"
+	"github.com/liserjrqlxue/goUtil/osUtil"
+	"github.com/liserjrqlxue/goUtil/simpleUtil"
+)
+
+// createScratchShell write job script run in a job-local scratch dir:
+// paths inside outdir of args and env are mapped to scratch, declared inputs inside outdir are staged in,
+// declared outputs are copied back to outdir on success, and scratch is removed on exit
+func createScratchShell(fileName, script, jobName string, env, inputs, outputs []string, args ...string) {
+	var file = osUtil.Create(fileName)
+	defer simpleUtil.DeferClose(file)
+
+	var base = `"${TMPDIR:-/tmp}"`
+	if *scratch != "" {
+		base = shellQuote(*scratch)
+	}
+	fmtUtil.Fprintf(file, "#!/bin/bash\n#$ -e %s\n#$ -o %s\n", filepath.Dir(fileName), filepath.Dir(fileName))
+	fmtUtil.Fprintf(file, "scratch=$(mktemp -d %s/%s) || exit 1\n", base, shellQuote(jobName+".XXXXXX"))
+	fmtUtil.Fprintf(file, "trap 'rm -rf \"$scratch\"' EXIT\ntrap 'exit 1' INT TERM\n")
+	for _, kv := range env {
+		var kvs = strings.SplitN(kv, "=", 2)
+		if path, ok := scratchPath(kvs[1]); ok {
+			fmtUtil.Fprintf(file, "export %s=%s\n", kvs[0], quoteScratch(path))
+			if strings.HasPrefix(kvs[0], "dir_") {
+				fmtUtil.Fprintf(file, "mkdir -p %s\n", quoteScratch(path))
+			}
+		} else {
+			fmtUtil.Fprintf(file, "%s\n", exportEnv(kv))
+		}
+	}
+	for _, input := range inputs {
+		var path, ok = scratchPath(input)
+		if !ok {
+			continue
+		}
+		if isGlob(input) {
+			// glob of base name is expanded by shell and copied into its dir
+			if isGlob(filepath.Dir(input)) {
+				log.Fatalf("glob in dir of input %s of scratch job %s", input, jobName)
+			}
+			var pattern = shellQuote(filepath.Dir(input)) + "/" + filepath.Base(input)
+			fmtUtil.Fprintf(file, "mkdir -p %s && cp -p %s %s/ || exit 1\n", quoteScratch(filepath.Dir(path)), pattern, quoteScratch(filepath.Dir(path)))
+		} else {
+			fmtUtil.Fprintf(file, "mkdir -p %s && cp -p %s %s || exit 1\n", quoteScratch(filepath.Dir(path)), shellQuote(input), quoteScratch(path))
+		}
+	}
+	// every arg of path inside outdir, e.g. outdir, sample dir or output dir
+	args = append([]string{}, args...)
+	for i, arg := range args {
+		if i > 0 && !strings.Contains(arg, "/") {
+			// sampleID or other name, not a path
+			continue
+		}
+		if path, ok := scratchPath(arg); ok {
+			args[i] = quoteScratch(path)
+		}
+	}
+	fmtUtil.Fprintf(file, "cd \"$scratch\"\nsh %s %s\n", script, strings.Join(args, " "))
+	fmtUtil.Fprintf(file, "status=$?\nif [ $status -ne 0 ];then exit $status;fi\n")
+	for _, output := range outputs {
+		var path, ok = scratchPath(output)
+		if !ok {
+			// written in place by script
+			path = shellQuote(output)
+		} else {
+			path = quoteScratch(path)
+		}
+		fmtUtil.Fprintf(file, "if [ ! -s %s ];then echo missing output:%s >&2;exit 100;fi\n", path, shellQuote(output))
+		if ok {
+			fmtUtil.Fprintf(file, "mkdir -p %s && cp -p %s %s || exit 1\n", shellQuote(filepath.Dir(output)), path, shellQuote(output))
+		}
+	}
+}
+
+// quoteScratch quote path from scratchPath, $scratch is expanded and the rest is literal
+func quoteScratch(path string) string {
+	if path == "$scratch" {
+		return `"$scratch"`
+	}
+	return `"$scratch"/` + shellQuote(strings.TrimPrefix(path, "$scratch/"))
+}
+
+// isGlob means path has shell pattern characters
+func isGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// scratchPath map path inside outdir to $scratch, false for path outside outdir
+func scratchPath(path string) (string, bool) {
+	var absOut, err = filepath.Abs(*outDir)
+	simpleUtil.CheckErr(err)
+	absPath, err := filepath.Abs(path)
+	simpleUtil.CheckErr(err)
+	rel, err := filepath.Rel(absOut, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return path, false
+	}
+	if rel == "." {
+		return "$scratch", true
+	}
+	return "$scratch/" + rel, true
+}
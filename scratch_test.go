@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateScratchShell(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("no bash")
+	}
+	var dir = t.TempDir()
+	*outDir = filepath.Join(dir, "out")
+	*scratch = filepath.Join(dir, "scratch")
+	var sampleDir = filepath.Join(*outDir, "S1")
+	for _, path := range []string{filepath.Join(sampleDir, "sub"), *scratch} {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"a.bam", "b.bam"} {
+		if err := os.WriteFile(filepath.Join(sampleDir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// count staged bam, and write output to the per-sample dir of 4th arg
+	var script = filepath.Join(dir, "step.sh")
+	var body = `ls $1/S1/*.bam | wc -l > $4/out.txt; echo $4 >> $4/out.txt; echo "$dir_sub" >> $4/out.txt`
+	if err := os.WriteFile(script, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var job = filepath.Join(dir, "job.sh")
+	var output = filepath.Join(sampleDir, "sub", "out.txt")
+	createScratchShell(
+		job, script, "step.S1",
+		[]string{"dir_sub=" + filepath.Join(sampleDir, "sub")},
+		[]string{filepath.Join(sampleDir, "*.bam")},
+		[]string{output},
+		*outDir, "/pipeline", "S1", filepath.Join(sampleDir, "sub"),
+	)
+	if out, err := exec.Command("bash", job).CombinedOutput(); err != nil {
+		t.Fatalf("%v:%s", err, out)
+	}
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines = strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 3 || strings.TrimSpace(lines[0]) != "2" {
+		t.Fatalf("output:%q", content)
+	}
+	for _, path := range lines[1:] {
+		if !strings.HasPrefix(path, *scratch) || !strings.HasSuffix(path, "/S1/sub") {
+			t.Errorf("path not mapped to scratch:%s", path)
+		}
+	}
+	if left, _ := filepath.Glob(filepath.Join(*scratch, "*")); len(left) > 0 {
+		t.Errorf("scratch not removed:%v", left)
+	}
+}
+
+func TestCreateScratchShellQuote(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("no bash")
+	}
+	var dir = filepath.Join(t.TempDir(), "a b")
+	*outDir = filepath.Join(dir, "out")
+	*scratch = filepath.Join(dir, "scratch dir")
+	var sampleDir = filepath.Join(*outDir, "S1")
+	var other = filepath.Join(dir, "other [1]")
+	for _, path := range []string{sampleDir, *scratch, other} {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var input = filepath.Join(sampleDir, "in put.txt")
+	if err := os.WriteFile(input, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// copy staged input to output in scratch, and write output outside outdir in place
+	var script = filepath.Join(dir, "step.sh")
+	var body = `cp "$1/S1/in put.txt" "$1/S1/out put.txt" && echo y > "$2"`
+	if err := os.WriteFile(script, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var job = filepath.Join(dir, "job.sh")
+	var output = filepath.Join(sampleDir, "out put.txt")
+	var inPlace = filepath.Join(other, "o*.txt")
+	createScratchShell(job, shellQuote(script), "step.S1", nil, []string{input}, []string{output, inPlace}, *outDir, shellQuote(inPlace))
+	if out, err := exec.Command("bash", job).CombinedOutput(); err != nil {
+		t.Fatalf("%v:%s", err, out)
+	}
+	if content, err := os.ReadFile(output); err != nil || string(content) != "x" {
+		t.Errorf("output:%q,%v", content, err)
+	}
+	if left, _ := filepath.Glob(filepath.Join(*scratch, "*")); len(left) > 0 {
+		t.Errorf("scratch not removed:%v", left)
+	}
+	// in place output matched by glob only does not pass check
+	if err := os.Remove(inPlace); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(script, []byte(`cp "$1/S1/in put.txt" "$1/S1/out put.txt" && echo y > "${2%/*}/o1.txt"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("bash", job).CombinedOutput(); err == nil || !strings.Contains(string(out), "missing output:"+inPlace) {
+		t.Errorf("%v:%s", err, out)
+	}
+}
@@ -3,7 +3,7 @@ workdir=$1
 pipeline=$2
 sampleID=$3
 
-Workdir=$workdir/$sampleID/bwa
+Workdir=${dir_bwa:-$workdir/$sampleID/bwa}
 export PATH=$pipeline/tools:$PATH
 
 hg19=$pipeline/hg19/hg19_chM_male_mask.fa
@@ -12,7 +12,7 @@ echo `date` Start ApplyBQSR
 
 gatk \
     ApplyBQSR \
-    --tmp-dir=$workdir/javatmp \
+    --tmp-dir=${dir_javatmp:-$workdir/javatmp} \
     -R $hg19 \
     -I $Workdir/$sampleID.realn.bam \
     -O $Workdir/$sampleID.bqsr.bam \
@@ -2,20 +2,33 @@
 workdir=$1
 pipeline=$2
 sampleID=$3
+laneList=$4
 
 Workdir=$workdir/$sampleID
+rawDir=${dir_raw:-$Workdir/raw}
+filterDir=${dir_filter:-$Workdir/filter}
 export PATH=$pipeline/tools:$PATH
 
-fq1=$Workdir/raw/${sampleID}.raw_1.fq.gz
-fq2=$Workdir/raw/${sampleID}.raw_2.fq.gz
+# filter one lane: lane fq1 fq2, empty lane for single fastq pair
+filter(){
+    name=$sampleID${1:+.$1}
+    java \
+        -jar $pipeline/tools/FastqQC.jar \
+        -o $filterDir \
+        -l 10 -q 0.1 -N 0.05 \
+        -1 $2 -2 $3 \
+        -C ${name}.filter_1.fq.gz \
+        -D ${name}.filter_2.fq.gz \
+        >$filterDir/${name}.reads_gc_qual.stat
+}
 
 echo `date` Start FastqQC
-java \
-    -jar $pipeline/tools/FastqQC.jar \
-    -o $Workdir/filter \
-    -l 10 -q 0.1 -N 0.05 \
-    -1 $fq1 -2 $fq2 \
-    -C ${sampleID}.filter_1.fq.gz \
-    -D ${sampleID}.filter_2.fq.gz \
-    >$Workdir/filter/${sampleID}.reads_gc_qual.stat \
-&&echo Done `date`
+if [ -z "$laneList" ];then
+    filter "" $rawDir/${sampleID}.raw_1.fq.gz $rawDir/${sampleID}.raw_2.fq.gz \
+    &&echo Done `date`
+else
+    tail -n +2 $laneList | while IFS=$'\t' read lane fq1 fq2;do
+        filter "$lane" $fq1 $fq2 || exit 1
+    done \
+    &&echo Done `date`
+fi
@@ -3,7 +3,7 @@ workdir=$1
 pipeline=$2
 sampleID=$3
 
-Workdir=$workdir/$sampleID/bwa
+Workdir=${dir_bwa:-$workdir/$sampleID/bwa}
 export PATH=$pipeline/tools:$PATH
 
 echo `date` Start MarkDuplicates
@@ -3,14 +3,14 @@ workdir=$1
 pipeline=$2
 sampleID=$3
 
-Workdir=$workdir/$sampleID/bwa
+Workdir=${dir_bwa:-$workdir/$sampleID/bwa}
 export PATH=$pipeline/tools:$PATH
 GATK=$pipeline/tools/GenomeAnalysisTK.jar
 Bed=$pipeline/config/cns_region_hg19_bychr/for500_region.bed
 hg19=$pipeline/hg19/hg19_chM_male_mask.fa
 
 echo `date` Start IndelRealigner
-java  -Djava.io.tmpdir=$workdir/javatmp \
+java  -Djava.io.tmpdir=${dir_javatmp:-$workdir/javatmp} \
     -jar $GATK \
     -T IndelRealigner \
     -R $hg19 \
@@ -2,18 +2,43 @@
 workdir=$1
 pipeline=$2
 sampleID=$3
+laneList=$4
 
 Workdir=$workdir/$sampleID
+filterDir=${dir_filter:-$Workdir/filter}
+bwaDir=${dir_bwa:-$Workdir/bwa}
 export PATH=$pipeline/tools:$PATH
 hg19=$pipeline/hg19/hg19_chM_male_mask.fa
+
+# align one lane: lane output, read group ID and PU are lane specific
+align(){
+    name=$sampleID${1:+.$1}
+    bwa \
+        mem -K 1000000 -t 8 -M \
+        -R "@RG\tID:$name\tSM:$sampleID\tLB:LB\tPL:COMPLETE${1:+\tPU:$1}" \
+        $hg19 \
+        $filterDir/$name.filter_1.fq.gz \
+        $filterDir/$name.filter_2.fq.gz \
+        | samtools view -S -b \
+        -o $2 \
+        -
+}
+
 echo `date` Start bwaMem
-bwa \
-    mem -K 1000000 -t 8 -M \
-    -R "@RG\tID:$sampleID\tSM:$sampleID\tLB:LB\tPL:COMPLETE" \
-    $hg19 \
-    $Workdir/filter/$sampleID.filter_1.fq.gz \
-    $Workdir/filter/$sampleID.filter_2.fq.gz \
-    | samtools view -S -b \
-    -o $Workdir/bwa/$sampleID.raw.bam \
-    - \
-&&echo `date` Done
+if [ -z "$laneList" ];then
+    align "" $bwaDir/$sampleID.raw.bam \
+    &&echo `date` Done
+else
+    bams=()
+    while IFS=$'\t' read lane fq1 fq2;do
+        bam=$bwaDir/$sampleID${lane:+.$lane}.raw.bam
+        align "$lane" $bam || exit 1
+        bams+=($bam)
+    done < <(tail -n +2 $laneList)
+    if [ ${#bams[@]} -eq 1 ];then
+        [ ${bams[0]} = $bwaDir/$sampleID.raw.bam ] || mv ${bams[0]} $bwaDir/$sampleID.raw.bam
+    else
+        samtools merge -f $bwaDir/$sampleID.raw.bam ${bams[@]}
+    fi \
+    &&echo `date` Done
+fi
@@ -0,0 +1,23 @@
+#!/usr/bin/env bash
+workdir=$1
+pipeline=$2
+barcode=$3
+list=$4
+subdir=${5:-raw}
+
+export PATH=$pipeline/tools:$PATH
+
+echo `date` Start splitBarcode
+$pipeline/splitBarcode/splitBarcode \
+    -input $list \
+    -barcode $barcode \
+    -outdir $workdir \
+    -subdir $subdir \
+    -statdir ${dir_barcode:-$workdir/barcode} \
+    -mismatch ${mismatch:-0} \
+    ${undetermined:+-undetermined $undetermined} \
+    ${threads:+-threads $threads} \
+    -level ${level:-6} \
+    -bgzf=${bgzf:-false} \
+    >${dir_barcode:-$workdir/barcode}/barcode.$barcode.stat \
+&&echo `date` Done
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/360EntSecGroup-Skylar/excelize/v2"
+	"github.com/liserjrqlxue/goUtil/simpleUtil"
+)
+
+// default column mapping of Illumina SampleSheet.csv and xlsx plate sheet, source -> target
+var sheetColumns = map[string]string{
+	"Sample_ID":      "sampleID",
+	"index":          "primer",
+	"Sample_Project": "barcode",
+	"Lane":           "lane",
+}
+
+// isSheet means input is SampleSheet.csv or xlsx instead of input list
+func isSheet(input string) bool {
+	switch strings.ToLower(filepath.Ext(input)) {
+	case ".csv", ".xlsx":
+		return true
+	}
+	return false
+}
+
+// readSheet read SampleSheet.csv or xlsx as title and rows of input list
+func readSheet(input string, inputErr *InputError) (title []string, rows []InputRow) {
+	var records [][]string
+	var lines []int
+	switch strings.ToLower(filepath.Ext(input)) {
+	case ".csv":
+		records, lines = readSampleSheet(input, inputErr)
+	case ".xlsx":
+		records, lines = readExcel(input, *sheet, inputErr)
+	}
+	for i, record := range records {
+		for j := range record {
+			record[j] = strings.TrimSpace(record[j])
+		}
+		if title == nil {
+			title = record
+			continue
+		}
+		if len(record) > len(title) {
+			inputErr.add(lines[i], "%d columns, title has %d", len(record), len(title))
+			continue
+		}
+		var item = make(map[string]string)
+		for j, key := range title {
+			if j < len(record) {
+				item[key] = record[j]
+			} else {
+				item[key] = ""
+			}
+		}
+		rows = append(rows, InputRow{Line: lines[i], Item: item})
+	}
+	return
+}
+
+// readSampleSheet read [Data] section of Illumina SampleSheet.csv, or whole file without sections
+func readSampleSheet(input string, inputErr *InputError) (records [][]string, lines []int) {
+	file, err := os.Open(input)
+	if err != nil {
+		inputErr.add(0, "%v", err)
+		return
+	}
+	defer simpleUtil.DeferClose(file)
+
+	var reader = csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	var section string
+	var lineNo int
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				inputErr.add(parseErr.StartLine, "%v", err)
+			} else {
+				inputErr.add(lineNo, "%v", err)
+			}
+			return
+		}
+		lineNo, _ = reader.FieldPos(0)
+		if isEmptyRecord(record) {
+			continue
+		}
+		var first = strings.TrimSpace(record[0])
+		if strings.HasPrefix(first, "[") && strings.HasSuffix(first, "]") {
+			section = first
+			continue
+		}
+		if section != "" && section != "[Data]" {
+			continue
+		}
+		// trailing empty cells written by Excel
+		for len(record) > 0 && strings.TrimSpace(record[len(record)-1]) == "" {
+			record = record[:len(record)-1]
+		}
+		records = append(records, record)
+		lines = append(lines, lineNo)
+	}
+	return
+}
+
+// readExcel read sheet of xlsx, first sheet if sheetName is empty
+func readExcel(input, sheetName string, inputErr *InputError) (records [][]string, lines []int) {
+	excel, err := excelize.OpenFile(input)
+	if err != nil {
+		inputErr.add(0, "%v", err)
+		return
+	}
+	if sheetName == "" {
+		sheetName, err = firstSheet(excel)
+		if err != nil {
+			inputErr.add(0, "%v", err)
+			return
+		}
+	}
+	rows, err := excel.GetRows(sheetName)
+	if err != nil {
+		inputErr.add(0, "sheet[%s]:%v", sheetName, err)
+		return
+	}
+	for i, record := range rows {
+		if isEmptyRecord(record) {
+			continue
+		}
+		records = append(records, record)
+		lines = append(lines, i+1)
+	}
+	return
+}
+
+// firstSheet return name of first sheet by position of workbook, not by SheetID
+func firstSheet(excel *excelize.File) (string, error) {
+	// GetSheetMap loads WorkBook
+	if len(excel.GetSheetMap()) == 0 || excel.WorkBook == nil || len(excel.WorkBook.Sheets.Sheet) == 0 {
+		return "", fmt.Errorf("no sheet in workbook")
+	}
+	return excel.WorkBook.Sheets.Sheet[0].Name, nil
+}
+
+func isEmptyRecord(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseColumnMap parse -columns: target=source,target=source
+func parseColumnMap(columns string) (columnMap map[string]string, err error) {
+	columnMap = make(map[string]string)
+	for _, kv := range splitList(columns) {
+		var kvs = strings.SplitN(kv, "=", 2)
+		if len(kvs) != 2 || kvs[0] == "" || kvs[1] == "" {
+			return nil, fmt.Errorf("-columns should be target=source:%q", kv)
+		}
+		columnMap[kvs[1]] = kvs[0]
+	}
+	return
+}
+
+// mapColumns rename source columns to target columns of input list,
+// sheet default mapping is used for target not set by -columns
+func mapColumns(title []string, rows []InputRow, sheetDefault bool, inputErr *InputError) []string {
+	var columnMap, err = parseColumnMap(*columns)
+	if err != nil {
+		inputErr.add(0, "%v", err)
+		return title
+	}
+	if sheetDefault {
+		var targets = make(map[string]bool)
+		for _, target := range columnMap {
+			targets[target] = true
+		}
+		for source, target := range sheetColumns {
+			if !targets[target] && !contains(title, target) && contains(title, source) {
+				columnMap[source] = target
+			}
+		}
+	}
+	if len(columnMap) == 0 {
+		return title
+	}
+	var newTitle []string
+	for _, key := range title {
+		if target, ok := columnMap[key]; ok {
+			if contains(title, target) {
+				inputErr.add(1, "column %s mapped to %s, which already exists", key, target)
+				return title
+			}
+			key = target
+		}
+		newTitle = append(newTitle, key)
+	}
+	for source := range columnMap {
+		if !contains(title, source) {
+			inputErr.add(1, "missing mapped column:%s", source)
+		}
+	}
+	for _, row := range rows {
+		for source, target := range columnMap {
+			if value, ok := row.Item[source]; ok {
+				row.Item[target] = value
+				delete(row.Item, source)
+			}
+		}
+	}
+	return newTitle
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/360EntSecGroup-Skylar/excelize/v2"
+)
+
+func TestReadSampleSheetMalformed(t *testing.T) {
+	var dir = t.TempDir()
+	var input = filepath.Join(dir, "SampleSheet.csv")
+	var sheet = "[Header]\nDate,2020\n[Data]\nSample_ID,index\nS1,\"ACGT\nS2,TTGT\n"
+	if err := os.WriteFile(input, []byte(sheet), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var inputErr = &InputError{File: input}
+	readSampleSheet(input, inputErr)
+	if len(inputErr.Problems) != 1 {
+		t.Fatalf("problems:%v", inputErr.Problems)
+	}
+	if !strings.HasPrefix(inputErr.Problems[0], input+":5: ") {
+		t.Errorf("problem without line of bad quote:%s", inputErr.Problems[0])
+	}
+}
+
+func TestReadExcelFirstSheet(t *testing.T) {
+	var excel = excelize.NewFile()
+	// first sheet by position has SheetID 2 after Sheet1 is deleted
+	excel.NewSheet("Plate")
+	excel.DeleteSheet("Sheet1")
+	excel.SetSheetRow("Plate", "A1", &[]string{"Sample_ID", "index"})
+	excel.SetSheetRow("Plate", "A2", &[]string{"S1", "ACGTACG"})
+	var input = filepath.Join(t.TempDir(), "plate.xlsx")
+	if err := excel.SaveAs(input); err != nil {
+		t.Fatal(err)
+	}
+	var inputErr = &InputError{File: input}
+	records, lines := readExcel(input, "", inputErr)
+	if len(inputErr.Problems) > 0 {
+		t.Fatalf("problems:%v", inputErr.Problems)
+	}
+	if len(records) != 2 || records[1][0] != "S1" || lines[1] != 2 {
+		t.Errorf("records:%v lines:%v", records, lines)
+	}
+}
@@ -0,0 +1,76 @@
+#!/usr/bin/env bash
+# benchmark splitBarcode of working tree against a git revision on random fastq:
+#   sh bench.sh revision [pairs] [samples]
+# report wall time, pairs/s and peak memory (VmHWM) of each
+set -e
+if [ $# -lt 1 ]; then
+  echo "usage: sh bench.sh revision [pairs] [samples]" >&2
+  exit 1
+fi
+rev=$1
+pairs=${2:-1000000}
+samples=${3:-24}
+
+here=$(cd $(dirname $0) && pwd)
+if ! git -C $here rev-parse --verify -q "$rev^{commit}" >/dev/null; then
+  echo "unknown revision:$rev" >&2
+  exit 1
+fi
+work=$(mktemp -d ${TMPDIR:-/tmp}/splitBarcode.bench.XXXXXX)
+trap "git -C $here worktree remove --force $work/base >/dev/null 2>&1; rm -rf $work" EXIT
+
+echo `date` build $rev and working tree
+git -C $here worktree add --detach $work/base "$rev" >/dev/null
+(cd $work/base/splitBarcode && go build -o $work/base.bin .)
+(cd $here && go build -o $work/new.bin .)
+
+echo `date` generate $pairs pairs of $samples samples
+awk -v pairs=$pairs -v samples=$samples -v list=$work/in.list -v fq1=$work/r1.fq -v fq2=$work/r2.fq '
+function seq(n,  s, i) { s = ""; for (i = 0; i < n; i++) s = s substr("ACGT", int(rand() * 4) + 1, 1); return s }
+function qual(n,  s, i) { s = ""; for (i = 0; i < n; i++) s = s "F"; return s }
+BEGIN {
+  srand(1)
+  print "sampleID\tprimer\tfq1\tfq2" > list
+  for (i = 0; i < samples; i++) {
+    # distinct primers
+    do { p = seq(10) } while (substr(p, 1, 7) in used)
+    used[substr(p, 1, 7)] = 1
+    primer[i] = p
+    print "S" i "\t" p "\t" fq1 ".gz\t" fq2 ".gz" > list
+  }
+  q = qual(150)
+  for (n = 0; n < pairs; n++) {
+    # 90% hit, 5% one read with random index, 5% no index
+    r = rand()
+    s = primer[int(rand() * samples)]
+    i1 = s; i2 = s
+    if (r > 0.95) { i1 = seq(10); i2 = seq(10) } else if (r > 0.9) { i2 = seq(10) }
+    print "@read" n "/1\n" i1 seq(140) "\n+\n" q > fq1
+    print "@read" n "/2\n" i2 seq(140) "\n+\n" q > fq2
+  }
+}'
+gzip -1 $work/r1.fq $work/r2.fq
+
+# run binary and poll peak memory of it
+run() {
+  local name=$1 bin=$2 hwm=0 start end
+  rm -rf $work/out.$name
+  start=$(date +%s.%N)
+  $bin -input $work/in.list -outdir $work/out.$name -barcode bench >$work/$name.stat 2>$work/$name.log &
+  local pid=$!
+  while [ -e /proc/$pid/status ]; do
+    local cur=$(awk '/VmHWM/{print $2}' /proc/$pid/status 2>/dev/null)
+    [ -n "$cur" ] && hwm=$cur
+    sleep 0.05
+  done
+  wait $pid
+  end=$(date +%s.%N)
+  awk -v name=$name -v pairs=$pairs -v start=$start -v end=$end -v hwm=${hwm:-0} 'BEGIN {
+    printf "%s\t%.2f\t%.0f\t%.1f\n", name, end - start, pairs / (end - start), hwm / 1024
+  }'
+}
+
+printf "binary\ttime(s)\tpairs/s\tpeakMem(MB)\n"
+run base $work/base.bin
+run new $work/new.bin
+cmp -s $work/base.stat $work/new.stat && echo stat is same || echo stat differs
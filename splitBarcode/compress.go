@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+
+	"github.com/klauspost/compress/flate"
+	"github.com/liserjrqlxue/DrugPipeline/xopen"
+	simple_util "github.com/liserjrqlxue/simple-util"
+)
+
+// max uncompressed bytes of a block, as BGZF of htslib:
+// outputs are cut into blocks compressed as independent gzip members by a pool of goroutines shared by all outputs,
+// members are BGZF blocks with -bgzf, any gzip reader reads them as one stream
+const blockSize = 0xff00
+
+// end of file marker of BGZF, an empty block
+var bgzfEOF = []byte{
+	0x1f, 0x8b, 0x08, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0x06, 0x00, 0x42, 0x43, 0x02, 0x00,
+	0x1b, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+}
+
+// blocks waiting for compression
+var compressQueue chan *block
+
+// startCompressors start threads goroutines compressing blocks at level
+func startCompressors(threads, level int) {
+	// check level before any output
+	var _, err = flate.NewWriter(io.Discard, level)
+	simple_util.CheckErr(err, "-level")
+	compressQueue = make(chan *block, threads)
+	for i := 0; i < threads; i++ {
+		go func() {
+			var fw, _ = flate.NewWriter(io.Discard, level)
+			for b := range compressQueue {
+				b.compress(fw)
+				close(b.done)
+			}
+		}()
+	}
+}
+
+type block struct {
+	data, member []byte
+	bgzf         bool
+	done         chan struct{}
+}
+
+// compress data to gzip member
+func (b *block) compress(fw *flate.Writer) {
+	var header = []byte{0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff}
+	if b.bgzf {
+		// FEXTRA with BC subfield, BSIZE filled after compression
+		header[3] = 0x04
+		header = append(header, 0x06, 0x00, 'B', 'C', 0x02, 0x00, 0x00, 0x00)
+	}
+	var buf = &memberBuffer{data: header}
+	fw.Reset(buf)
+	_, err := fw.Write(b.data)
+	simple_util.CheckErr(err)
+	simple_util.CheckErr(fw.Close())
+	var member = buf.data
+	member = binary.LittleEndian.AppendUint32(member, crc32.ChecksumIEEE(b.data))
+	member = binary.LittleEndian.AppendUint32(member, uint32(len(b.data)))
+	if b.bgzf {
+		binary.LittleEndian.PutUint16(member[16:], uint16(len(member)-1))
+	}
+	b.member = member
+}
+
+type memberBuffer struct {
+	data []byte
+}
+
+func (buf *memberBuffer) Write(p []byte) (int, error) {
+	buf.data = append(buf.data, p...)
+	return len(p), nil
+}
+
+// BlockWriter compress data written to it by compressQueue and write members to w in order
+type BlockWriter struct {
+	w    io.Writer
+	bgzf bool
+	buf  []byte
+	// blocks flushed
+	n int
+	// blocks in order of data, bounded by threads
+	pending chan *block
+	drained chan struct{}
+}
+
+func NewBlockWriter(w io.Writer, bgzf bool, threads int) *BlockWriter {
+	var bw = &BlockWriter{
+		w:       w,
+		bgzf:    bgzf,
+		buf:     make([]byte, 0, blockSize),
+		pending: make(chan *block, threads),
+		drained: make(chan struct{}),
+	}
+	go bw.drain()
+	return bw
+}
+
+func (bw *BlockWriter) Write(p []byte) (int, error) {
+	var n = len(p)
+	for len(p) > 0 {
+		var k = blockSize - len(bw.buf)
+		if k > len(p) {
+			k = len(p)
+		}
+		bw.buf = append(bw.buf, p[:k]...)
+		p = p[k:]
+		if len(bw.buf) == blockSize {
+			bw.flush()
+		}
+	}
+	return n, nil
+}
+
+func (bw *BlockWriter) flush() {
+	var b = &block{data: bw.buf, bgzf: bw.bgzf, done: make(chan struct{})}
+	bw.buf = make([]byte, 0, blockSize)
+	bw.n++
+	bw.pending <- b
+	compressQueue <- b
+}
+
+func (bw *BlockWriter) drain() {
+	defer close(bw.drained)
+	for b := range bw.pending {
+		<-b.done
+		_, err := bw.w.Write(b.member)
+		simple_util.CheckErr(err)
+	}
+}
+
+// Close flush last block and wait for all members written, BGZF ends with EOF marker,
+// and empty gzip has one empty member as gzip.Writer
+func (bw *BlockWriter) Close() (err error) {
+	if len(bw.buf) > 0 || (bw.n == 0 && !bw.bgzf) {
+		bw.flush()
+	}
+	close(bw.pending)
+	<-bw.drained
+	if bw.bgzf {
+		_, err = bw.w.Write(bgzfEOF)
+	}
+	return
+}
+
+// readFq open plain, gzip, BGZF or zstd fastq, blocks of BGZF inflated in parallel and others decompressed ahead of scanner
+func readFq(path string) (reader *xopen.Reader, scanner *bufio.Scanner) {
+	var err error
+	reader, err = xopen.Open(path, *threads)
+	simple_util.CheckErr(err)
+	log.Printf("read %s fastq %s", reader.Format, path)
+	scanner = bufio.NewScanner(reader)
+	return
+}
+
+func writeFq(path string) (file *os.File, writer *BlockWriter) {
+	var err error
+	file, err = os.Create(path)
+	simple_util.CheckErr(err)
+	writer = NewBlockWriter(file, *bgzf, *threads)
+	return
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/liserjrqlxue/DrugPipeline/xopen"
+)
+
+func TestBlockWriter(t *testing.T) {
+	startCompressors(4, 6)
+	var data = []byte(strings.Repeat("@read\nACGTACGTTTGA\n+\nIIIIIIIIIIII\n", 10000))
+	for _, isBGZF := range []bool{false, true} {
+		for _, input := range [][]byte{nil, data} {
+			var out bytes.Buffer
+			var bw = NewBlockWriter(&out, isBGZF, 4)
+			// uneven writes across block boundaries
+			for p := input; len(p) > 0; {
+				var k = 7777
+				if k > len(p) {
+					k = len(p)
+				}
+				bw.Write(p[:k])
+				p = p[k:]
+			}
+			if err := bw.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if isBGZF && !bytes.HasSuffix(out.Bytes(), bgzfEOF) {
+				t.Errorf("no BGZF EOF marker")
+			}
+			gz, err := gzip.NewReader(bytes.NewReader(out.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(gz)
+			if err != nil || !bytes.Equal(got, input) {
+				t.Errorf("bgzf:%v gzip read %d bytes,%v want %d", isBGZF, len(got), err, len(input))
+			}
+			reader, err := xopen.NewReader(bytes.NewReader(out.Bytes()), 4)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if isBGZF && len(input) > 0 && reader.Format != xopen.BGZF {
+				t.Errorf("format:%s want BGZF", reader.Format)
+			}
+			got, err = io.ReadAll(reader)
+			if err != nil || !bytes.Equal(got, input) {
+				t.Errorf("bgzf:%v xopen read %d bytes,%v want %d", isBGZF, len(got), err, len(input))
+			}
+			reader.Close()
+		}
+	}
+}
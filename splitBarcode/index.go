@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Index is where index is read and how many bases are trimmed of reads of one lane
+type Index struct {
+	// index is read[Start:Start+Length], matched to primer[:Length]
+	Start, Length int
+	// trim first Trim bases of sequence and quality of both reads
+	Trim int
+}
+
+// index columns of list, override -indexStart -indexLength -trim
+var indexColumns = []string{"indexStart", "indexLength", "trim"}
+
+// parseIndex return index of item, from its index columns or flags
+func parseIndex(item map[string]string) (index Index, err error) {
+	index = Index{Start: *indexStart, Length: *indexLength, Trim: *trim}
+	for i, value := range []*int{&index.Start, &index.Length, &index.Trim} {
+		var column = indexColumns[i]
+		if item[column] == "" {
+			continue
+		}
+		if *value, err = strconv.Atoi(item[column]); err != nil {
+			return index, fmt.Errorf("%s of sample[%s] is not integer:%q", column, item["sampleID"], item[column])
+		}
+	}
+	if index.Start < 0 || index.Length <= 0 || index.Trim < 0 {
+		return index, fmt.Errorf("invalid index of sample[%s]:%+v", item["sampleID"], index)
+	}
+	return
+}
+
+// primer return index of primer, error if primer is too short
+func (index Index) primer(primer string) (string, error) {
+	if len(primer) < index.Length {
+		return "", fmt.Errorf("primer[%s] shorter than index length %d", primer, index.Length)
+	}
+	return primer[:index.Length], nil
+}
+
+// read return index of read sequence, false if sequence is too short to read index and trim
+func (index Index) read(seq string) (string, bool) {
+	if len(seq) < index.Start+index.Length || len(seq) < index.Trim {
+		return "", false
+	}
+	return seq[index.Start : index.Start+index.Length], true
+}
+
+// max entries of corrected index cache of Matcher
+const maxCorrectCache = 1 << 20
+
+// Matcher match index of read to sample, allowing up to mismatch Hamming distance
+type Matcher struct {
+	index    Index
+	mismatch int
+	// primer index -> sampleID
+	exact   map[string]string
+	primers []string
+	// read index -> sampleID corrected, "" for no unique match
+	cache map[string]string
+}
+
+// newMatcher of samples of lane, error if two primers have same index,
+// or are within 2*mismatch of each other so that a read index can be corrected to at most one sample
+func newMatcher(index Index, mismatch int, samples []*Sample) (*Matcher, error) {
+	var matcher = &Matcher{
+		index:    index,
+		mismatch: mismatch,
+		exact:    make(map[string]string),
+		cache:    make(map[string]string),
+	}
+	for _, sample := range samples {
+		if other, ok := matcher.exact[sample.NewPrimer]; ok {
+			return nil, fmt.Errorf(
+				"primer[%s] of sample[%s] and primer of sample[%s] have same index[%s]",
+				sample.primer, sample.SampleID, other, sample.NewPrimer,
+			)
+		}
+		if mismatch > 0 {
+			for _, primer := range matcher.primers {
+				var other = matcher.exact[primer]
+				if d := hamming(primer, sample.NewPrimer); d > 0 && d <= 2*mismatch {
+					return nil, fmt.Errorf(
+						"primer index[%s] of sample[%s] and [%s] of sample[%s] differ by %d, not more than 2*mismatch(%d)",
+						sample.NewPrimer, sample.SampleID, primer, other, d, mismatch,
+					)
+				}
+			}
+		}
+		matcher.exact[sample.NewPrimer] = sample.SampleID
+		matcher.primers = append(matcher.primers, sample.NewPrimer)
+	}
+	return matcher, nil
+}
+
+// match return sampleID of index of read sequence, corrected is true if matched with mismatch
+func (matcher *Matcher) match(seq string) (sampleID string, corrected, ok bool) {
+	key, ok := matcher.index.read(seq)
+	if !ok {
+		return "", false, false
+	}
+	if sampleID, ok = matcher.exact[key]; ok || matcher.mismatch == 0 {
+		return sampleID, false, ok
+	}
+	sampleID, ok = matcher.cache[key]
+	if !ok {
+		for _, primer := range matcher.primers {
+			if hamming(key, primer) <= matcher.mismatch {
+				sampleID = matcher.exact[primer]
+				break
+			}
+		}
+		if len(matcher.cache) < maxCorrectCache {
+			matcher.cache[key] = sampleID
+		}
+	}
+	return sampleID, sampleID != "", sampleID != ""
+}
+
+// clone share primers of matcher with a new cache, for use in another goroutine
+func (matcher *Matcher) clone() *Matcher {
+	var other = *matcher
+	other.cache = make(map[string]string)
+	return &other
+}
+
+// hamming distance of equal length strings
+func hamming(a, b string) (d int) {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			d++
+		}
+	}
+	return
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func testSamples(primers ...string) (samples []*Sample) {
+	for i, primer := range primers {
+		samples = append(samples, &Sample{SampleID: "S" + string(rune('1'+i)), primer: primer, NewPrimer: primer[:7]})
+	}
+	return
+}
+
+func TestNewMatcher(t *testing.T) {
+	var index = Index{Length: 7}
+	var tests = []struct {
+		mismatch int
+		primers  []string
+		err      string
+	}{
+		{0, []string{"ACGTACGTT", "ACGTACCTT"}, ""},
+		{0, []string{"ACGTACGTT", "ACGTACGAA"}, "same index[ACGTACG]"},
+		{1, []string{"ACGTACGTT", "ACGTTTGTT"}, "differ by 2"},
+		{1, []string{"ACGTACGTT", "ACGAATCTT"}, ""},
+		{2, []string{"ACGTACGTT", "ACGAATCTT"}, "differ by 3"},
+	}
+	for _, test := range tests {
+		var _, err = newMatcher(index, test.mismatch, testSamples(test.primers...))
+		if (test.err == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("newMatcher(%d,%v) error:%v want %q", test.mismatch, test.primers, err, test.err)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	var samples = testSamples("ACGTACGTT", "TTTTGGGCC")
+	var tests = []struct {
+		mismatch  int
+		start     int
+		seq       string
+		sampleID  string
+		corrected bool
+		ok        bool
+	}{
+		{0, 0, "ACGTACGNNNN", "S1", false, true},
+		{0, 0, "ACGTACCNNNN", "", false, false},
+		{1, 0, "ACGTACCNNNN", "S1", true, true},
+		{1, 0, "TTTTGGGAAAA", "S2", false, true},
+		{1, 0, "ACGTTTCNNNN", "", false, false},
+		// index read at start
+		{0, 2, "NNTTTTGGGAA", "S2", false, true},
+		// too short to read index
+		{1, 0, "ACGTAC", "", false, false},
+	}
+	for _, test := range tests {
+		var matcher, err = newMatcher(Index{Start: test.start, Length: 7}, test.mismatch, samples)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// twice for cached correction
+		for i := 0; i < 2; i++ {
+			var sampleID, corrected, ok = matcher.match(test.seq)
+			if sampleID != test.sampleID || corrected != test.corrected || ok != test.ok {
+				t.Errorf("mismatch %d match(%s)=%s,%v,%v want %s,%v,%v", test.mismatch, test.seq, sampleID, corrected, ok, test.sampleID, test.corrected, test.ok)
+			}
+		}
+	}
+}
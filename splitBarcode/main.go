@@ -11,6 +11,7 @@ import (
 	"runtime/pprof"
 	"strings"
 	"sync"
+	"time"
 )
 
 var (
@@ -27,12 +28,12 @@ var (
 	fq1 = flag.String(
 		"fq1",
 		"",
-		"fq1",
+		"fq1, override fq1 and lane of -input",
 	)
 	fq2 = flag.String(
 		"fq2",
 		"",
-		"fq2",
+		"fq2, override fq2 and lane of -input",
 	)
 	barcode = flag.String(
 		"barcode",
@@ -44,6 +45,56 @@ var (
 		"raw",
 		"output split data to outdir/sampleID/subDir/",
 	)
+	indexStart = flag.Int(
+		"indexStart",
+		0,
+		"0-based start of index in read, override by indexStart column of -input",
+	)
+	indexLength = flag.Int(
+		"indexLength",
+		7,
+		"index length, matched to prefix of primer, override by indexLength column of -input",
+	)
+	trim = flag.Int(
+		"trim",
+		8,
+		"bases trimmed from start of reads, override by trim column of -input",
+	)
+	mismatch = flag.Int(
+		"mismatch",
+		0,
+		"max Hamming distance allowed between read index and primer index",
+	)
+	undetermined = flag.String(
+		"undetermined",
+		"",
+		"write pairs without index of same sample to Undetermined_[12].fq.gz, and pairs of different samples to IndexConflict_[12].fq.gz in this dir",
+	)
+	statDir = flag.String(
+		"statdir",
+		"",
+		"dir of stat files [barcode.]*, default -outdir",
+	)
+	topUnmatched = flag.Int(
+		"topUnmatched",
+		20,
+		"report top N index sequences of unmatched reads to [barcode.]unmatched.tsv, 0 to skip",
+	)
+	threads = flag.Int(
+		"threads",
+		runtime.NumCPU(),
+		"threads to decompress, match and compress",
+	)
+	level = flag.Int(
+		"level",
+		6,
+		"gzip compression level of outputs, 0-9",
+	)
+	bgzf = flag.Bool(
+		"bgzf",
+		false,
+		"write outputs as BGZF, indexable by htslib tools",
+	)
 	cpuProfile = flag.String(
 		"cpu",
 		"",
@@ -58,14 +109,25 @@ var (
 
 var err error
 
+// start time of run
+var start = time.Now()
+
 func main() {
 	log.Printf("Start:%+v", os.Args)
 	flag.Parse()
-	if *input == "" || *fq1 == "" || *fq2 == "" {
+	if *input == "" {
 		flag.Usage()
-		log.Printf("-input,-fq1,-fq2 required!")
+		log.Printf("-input required!")
 		os.Exit(0)
 	}
+	if *statDir == "" {
+		*statDir = *outDir
+	}
+	if *threads < 1 {
+		*threads = 1
+	}
+	runtime.GOMAXPROCS(*threads)
+	startCompressors(*threads, *level)
 
 	if *cpuProfile != "" {
 		f, err := os.Create(*cpuProfile)
@@ -76,107 +138,172 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
-	key := strings.Join([]string{*barcode, *fq1, *fq2}, "\t")
-	pe := &PE{}
-	pe.create(*barcode, key, *fq1, *fq2)
-
 	inputInfo, _ := simpleUtil.File2MapArray(*input, "\t", nil)
+	var lanes = parseLanes(inputInfo)
+	if len(lanes) == 0 {
+		flag.Usage()
+		log.Printf("-fq1,-fq2 required if -input has no fq1 and fq2!")
+		os.Exit(0)
+	}
+
+	var total = &PE{barcode: *barcode, unmatched: newIndexCount(), hopping: make(map[[2]string]uint64)}
+	var SampleInfo = make(map[string][]*Sample)
+	var sampleOrder []string
+	var unmatched []*laneUnmatched
+	for _, lane := range lanes {
+		var pe, samples = splitLane(lane)
+		total.add(pe)
+		unmatched = append(unmatched, newLaneUnmatched(lane.Lane, pe, samples))
+		for _, sample := range samples {
+			if _, ok := SampleInfo[sample.SampleID]; !ok {
+				sampleOrder = append(sampleOrder, sample.SampleID)
+			}
+			SampleInfo[sample.SampleID] = append(SampleInfo[sample.SampleID], sample)
+		}
+	}
+
+	if *topUnmatched > 0 {
+		writeUnmatched(statFile("unmatched.tsv"), unmatched, *topUnmatched)
+	}
+
+	writeHopping(statFile("index_hopping.tsv"), total.hopping, sampleOrder)
+	newBarcodeStat(total, sampleOrder, SampleInfo).write()
+
+	log.Printf("sampleID\tlane\thitNum\twritenum\texact\tcorrected\n")
+	for _, sampleID := range sampleOrder {
+		for _, sample := range SampleInfo[sampleID] {
+			log.Printf("%s\t%s\t%d\t%d\t%d\t%d\n", sample.SampleID, sample.lane, sample.hitNum, sample.writeNum, sample.exactNum, sample.correctedNum)
+		}
+	}
+
+	if *memProfile != "" {
+		simpleUtil.MemProfile(*memProfile)
+	}
+	logUsage(total.peNo)
+	log.Printf("End")
+	fmt.Println(strings.Join([]string{"Barcode", "拆之前reads num", "两端相同index", "两端不同index", "只有一端有index", "两端都没有index", "有效数据利用率"}, "\t"))
+	fmt.Printf("%s\t%d\t%d\t%d\t%d\t%d\t%f\n", total.barcode, total.peNo, total.hitNo, total.diffIndex, total.singleIndex, total.nonIndex, fraction(total.hitNo, total.peNo))
+}
+
+// Lane is one fastq pair of barcode and samples sequenced on it
+type Lane struct {
+	Lane     string
+	Fq1, Fq2 string
+	Index    Index
+	Items    []map[string]string
+}
+
+// parseLanes group rows of list by lane, -fq1 and -fq2 override fastq of list as one lane
+func parseLanes(inputInfo []map[string]string) (lanes []*Lane) {
+	var laneMap = make(map[string]*Lane)
+	for _, item := range inputInfo {
+		var laneName, fastq1, fastq2 = item["lane"], item["fq1"], item["fq2"]
+		if *fq1 != "" || *fq2 != "" {
+			laneName, fastq1, fastq2 = "", *fq1, *fq2
+		}
+		if fastq1 == "" || fastq2 == "" {
+			continue
+		}
+		var index, err = parseIndex(item)
+		simpleUtil.CheckErr(err)
+		var lane, ok = laneMap[laneName]
+		if !ok {
+			lane = &Lane{Lane: laneName, Fq1: fastq1, Fq2: fastq2, Index: index}
+			laneMap[laneName] = lane
+			lanes = append(lanes, lane)
+		} else if lane.Fq1 != fastq1 || lane.Fq2 != fastq2 {
+			log.Fatalf("lane[%s] has different fastq:[%s %s]vs[%s %s]", laneName, lane.Fq1, lane.Fq2, fastq1, fastq2)
+		} else if lane.Index != index {
+			log.Fatalf("lane[%s] has different index of sample[%s]:%+v vs %+v", laneName, item["sampleID"], index, lane.Index)
+		}
+		lane.Items = append(lane.Items, item)
+	}
+	return
+}
+
+// splitLane split one fastq pair to samples of the lane
+func splitLane(lane *Lane) (pe *PE, samples []*Sample) {
+	key := strings.Join([]string{*barcode, lane.Lane, lane.Fq1, lane.Fq2}, "\t")
+	pe = &PE{}
+	pe.create(*barcode, key, lane.Fq1, lane.Fq2)
+
 	var SampleInfo = make(map[string]*Sample)
-	var barcodeMap = make(map[string]string)
 	var wg sync.WaitGroup
-	for _, item := range inputInfo {
+	for _, item := range lane.Items {
 		sampleID := item["sampleID"]
 		// SampleInfo
 		sample, ok := SampleInfo[sampleID]
 		if ok {
-			log.Fatalf("sample[%s] duplicate", sampleID)
+			log.Fatalf("sample[%s] duplicate in lane[%s]", sampleID, lane.Lane)
 		} else {
 			sample = &Sample{}
-			sample.create(item, key, filepath.Join(*outDir, sampleID, *subDir))
+			sample.create(item, key, lane.Lane, filepath.Join(*outDir, sampleID, *subDir), lane.Index)
 			SampleInfo[sampleID] = sample
-			wg.Add(1)
-			go sample.write(&wg)
+			samples = append(samples, sample)
 		}
-		barcodeMap[sample.NewPrimer] = sampleID
+	}
+	var matcher, err = newMatcher(lane.Index, *mismatch, samples)
+	simpleUtil.CheckErr(err, "lane["+lane.Lane+"]")
+	for _, sample := range samples {
+		wg.Add(1)
+		go sample.write(&wg)
+	}
+	// outputs of pairs not split to samples
+	var undeterminedOut, conflictOut *Sample
+	var outputs = samples
+	if *undetermined != "" {
+		undeterminedOut, conflictOut = &Sample{}, &Sample{}
+		undeterminedOut.createOutput("Undetermined", *barcode, lane.Lane, *undetermined)
+		conflictOut.createOutput("IndexConflict", *barcode, lane.Lane, *undetermined)
+		outputs = append([]*Sample{undeterminedOut, conflictOut}, samples...)
+		wg.Add(2)
+		go undeterminedOut.write(&wg)
+		go conflictOut.write(&wg)
 	}
 
-	throttle = make(chan bool, 1e6)
 	log.Printf("load pe[%s]", pe.Key)
-	var loop = true
-	var read1, read2 [4]string
-	for loop {
-		for i := 0; i < 4; i++ {
-			loop = pe.S1.Scan() && pe.S2.Scan()
-			if !loop {
-				break
-			}
-			read1[i] = pe.S1.Text()
-			read2[i] = pe.S2.Text()
-		}
-		if !loop {
-			break
-		}
-		pe.peNo++
-		throttle <- true
-		n = runtime.NumGoroutine()
-		if maxNumGoroutine < n {
-			maxNumGoroutine = n
-		}
-		readName1 := strings.Split(read1[0], "/")[0]
-		readName2 := strings.Split(read2[0], "/")[0]
-		if readName1 != readName2 {
-			log.Fatalf("PE:%d[%s!=%s]", pe.peNo, readName1, readName2)
-		}
-		sample1, ok1 := barcodeMap[read1[1][:7]]
-		sample2, ok2 := barcodeMap[read2[1][:7]]
-		if ok1 && ok2 {
-			if sample1 != sample2 {
-				pe.diffIndex++
-				<-throttle
-			} else {
-				pe.hitNo++
-				sample := SampleInfo[sample1]
-				go splitReads(read1, read2, sample)
-			}
-		} else if ok1 || ok2 {
-			pe.singleIndex++
-			<-throttle
-		} else {
-			pe.nonIndex++
-			<-throttle
-		}
+	var batches = make(chan []Pair, *threads*batchQueue)
+	go readBatches(pe, batches)
+	var workerList []*Worker
+	var workerWg sync.WaitGroup
+	for i := 0; i < *threads; i++ {
+		var worker = newWorker(lane, matcher, SampleInfo, undeterminedOut, conflictOut)
+		workerList = append(workerList, worker)
+		workerWg.Add(1)
+		go func() {
+			defer workerWg.Done()
+			worker.run(batches)
+		}()
 	}
-	simpleUtil.CheckErr(pe.S1.Err())
-	simpleUtil.CheckErr(pe.S2.Err())
+	workerWg.Wait()
 	log.Printf("close pe[%s]", pe.Key)
 	pe.close()
 
-	log.Printf("split finish:%d", runtime.NumGoroutine())
-	for i := 0; i < 1e6; i++ {
-		throttle <- true
+	for _, worker := range workerList {
+		pe.add(worker.stat)
+		for sampleID, count := range worker.sampleCount {
+			SampleInfo[sampleID].exactNum += count[0]
+			SampleInfo[sampleID].correctedNum += count[1]
+		}
 	}
-	log.Printf("split finish:%d", runtime.NumGoroutine())
-
-	// wait close done
-	for _, sample := range SampleInfo {
-		go sample.close()
+	for _, sample := range samples {
+		sample.hitNum = sample.exactNum + sample.correctedNum
+	}
+	for _, output := range outputs {
+		close(output.FQ)
 	}
-	log.Printf("split finish:%d", runtime.NumGoroutine())
 
-	log.Printf("wait for done\n")
 	// wait write done
 	wg.Wait()
+	return
+}
 
-	log.Printf("sampleID\thitNum\twritenum\n")
-	for _, sample := range SampleInfo {
-		log.Printf("%s\t%d\t%d\n", sample.SampleID, sample.hitNum, sample.writeNum)
-	}
-
-	if *memProfile != "" {
-		simpleUtil.MemProfile(*memProfile)
+// logUsage log throughput and peak memory of run
+func logUsage(pairs uint64) {
+	var elapsed = time.Since(start).Seconds()
+	var msg = fmt.Sprintf("pairs:%d\ttime:%.2fs\tpairs/s:%.0f", pairs, elapsed, float64(pairs)/elapsed)
+	if rss, ok := peakRSS(); ok {
+		msg += fmt.Sprintf("\tpeakRSS:%.1fMB", rss)
 	}
-	log.Printf("End")
-	defer log.Printf("maxGoroutine:%d", maxNumGoroutine)
-	fmt.Println(strings.Join([]string{"Barcode", "拆之前reads num", "两端相同index", "两端不同index", "只有一端有index", "两端都没有index", "有效数据利用率"}, "\t"))
-	fmt.Printf("%s\t%d\t%d\t%d\t%d\t%d\t%f\n", pe.barcode, pe.peNo, pe.hitNo, pe.diffIndex, pe.singleIndex, pe.nonIndex, float64(pe.hitNo)/float64(pe.peNo))
+	log.Print(msg)
 }
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	simpleUtil "github.com/liserjrqlxue/simple-util"
+)
+
+// pipeline of one lane: reader -> batches -> workers -> chunks -> sample writers,
+// every channel is bounded so memory does not grow with input
+const (
+	// pairs of one batch
+	batchSize = 4096
+	// batches queued for workers, per worker
+	batchQueue = 2
+	// chunks queued for each sample writer
+	chunkQueue = 4
+)
+
+// Pair is one read pair, 4 lines of each read
+type Pair struct {
+	read1, read2 [4]string
+}
+
+// Chunk is fastq text of pairs of one output from one batch
+type Chunk struct {
+	fq1, fq2 []byte
+	n        uint64
+}
+
+// append pair to chunk, trim first trim bases of sequence and quality
+func (chunk *Chunk) append(pair *Pair, trim int) {
+	chunk.fq1 = appendRead(chunk.fq1, &pair.read1, trim)
+	chunk.fq2 = appendRead(chunk.fq2, &pair.read2, trim)
+	chunk.n++
+}
+
+func appendRead(buf []byte, read *[4]string, trim int) []byte {
+	for i, line := range read {
+		if i == 1 || i == 3 {
+			line = line[trim:]
+		}
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+	return buf
+}
+
+// readBatches read pairs of pe to batches, batches is closed at end of input
+func readBatches(pe *PE, batches chan<- []Pair) {
+	defer close(batches)
+	var batch = make([]Pair, 0, batchSize)
+	for {
+		var pair Pair
+		var loop = true
+		for i := 0; i < 4; i++ {
+			loop = pe.S1.Scan() && pe.S2.Scan()
+			if !loop {
+				break
+			}
+			pair.read1[i] = pe.S1.Text()
+			pair.read2[i] = pe.S2.Text()
+		}
+		if !loop {
+			break
+		}
+		batch = append(batch, pair)
+		if len(batch) == batchSize {
+			batches <- batch
+			batch = make([]Pair, 0, batchSize)
+		}
+	}
+	if len(batch) > 0 {
+		batches <- batch
+	}
+	simpleUtil.CheckErr(pe.S1.Err())
+	simpleUtil.CheckErr(pe.S2.Err())
+}
+
+// Worker classify pairs of batches and send chunks to sample writers, counts are kept in its own stat
+type Worker struct {
+	lane    *Lane
+	matcher *Matcher
+	samples map[string]*Sample
+	// outputs of pairs not split, nil if not written
+	undetermined, conflict *Sample
+	stat                   *PE
+	// sampleID -> exact, corrected pairs
+	sampleCount map[string]*[2]uint64
+}
+
+func newWorker(lane *Lane, matcher *Matcher, samples map[string]*Sample, undetermined, conflict *Sample) *Worker {
+	var worker = &Worker{
+		lane:         lane,
+		matcher:      matcher.clone(),
+		samples:      samples,
+		undetermined: undetermined,
+		conflict:     conflict,
+		stat:         &PE{},
+		sampleCount:  make(map[string]*[2]uint64),
+	}
+	worker.stat.initStat()
+	for sampleID := range samples {
+		worker.sampleCount[sampleID] = &[2]uint64{}
+	}
+	return worker
+}
+
+func (worker *Worker) run(batches <-chan []Pair) {
+	for batch := range batches {
+		var chunks = make(map[*Sample]*Chunk)
+		for i := range batch {
+			var output, trim = worker.classify(&batch[i])
+			if output == nil {
+				continue
+			}
+			var chunk, ok = chunks[output]
+			if !ok {
+				chunk = &Chunk{}
+				chunks[output] = chunk
+			}
+			chunk.append(&batch[i], trim)
+		}
+		for output, chunk := range chunks {
+			output.FQ <- chunk
+		}
+	}
+}
+
+// classify pair, count it and return output and trim of pair, nil if pair is dropped
+func (worker *Worker) classify(pair *Pair) (*Sample, int) {
+	var pe = worker.stat
+	pe.peNo++
+	readName1 := strings.Split(pair.read1[0], "/")[0]
+	readName2 := strings.Split(pair.read2[0], "/")[0]
+	if readName1 != readName2 {
+		log.Fatalf("PE[%s!=%s]", readName1, readName2)
+	}
+	sample1, corrected1, ok1 := worker.matcher.match(pair.read1[1])
+	sample2, corrected2, ok2 := worker.matcher.match(pair.read2[1])
+	if ok1 && ok2 {
+		pe.hopping[[2]string{sample1, sample2}]++
+		if sample1 != sample2 {
+			pe.diffIndex++
+			return worker.conflict, 0
+		}
+		pe.hitNo++
+		if corrected1 || corrected2 {
+			worker.sampleCount[sample1][1]++
+		} else {
+			worker.sampleCount[sample1][0]++
+		}
+		return worker.samples[sample1], worker.lane.Index.Trim
+	}
+	if ok1 || ok2 {
+		pe.singleIndex++
+	} else {
+		pe.nonIndex++
+	}
+	if *topUnmatched > 0 {
+		pe.countUnmatched(worker.lane.Index, pair.read1[1], pair.read2[1], ok1, ok2)
+	}
+	return worker.undetermined, 0
+}
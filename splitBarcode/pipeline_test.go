@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// testLane return lane of n samples with primers of index distance above 2, and its samples
+func testLane(n int) (*Lane, []*Sample) {
+	var r = rand.New(rand.NewSource(1))
+	var lane = &Lane{Index: Index{Length: 7}}
+	var samples []*Sample
+	for len(samples) < n {
+		var primer = randomSeq(r, 10)
+		var ok = true
+		for _, sample := range samples {
+			if hamming(sample.NewPrimer, primer[:7]) <= 2 {
+				ok = false
+			}
+		}
+		if ok {
+			samples = append(samples, &Sample{SampleID: fmt.Sprintf("S%d", len(samples)), primer: primer, NewPrimer: primer[:7]})
+		}
+	}
+	return lane, samples
+}
+
+func randomSeq(r *rand.Rand, n int) string {
+	var seq = make([]byte, n)
+	for i := range seq {
+		seq[i] = "ACGT"[r.Intn(4)]
+	}
+	return string(seq)
+}
+
+// testPairs return pairs of samples as bench.sh: 90% hit, 5% one read with random index, 5% no index,
+// and 1 of 10 hit reads has one mismatch in index
+func testPairs(n int, samples []*Sample) []Pair {
+	var r = rand.New(rand.NewSource(2))
+	var qual = string(make([]byte, 150))
+	var pairs = make([]Pair, n)
+	for i := range pairs {
+		var primer = []byte(samples[r.Intn(len(samples))].primer)
+		if r.Intn(10) == 0 {
+			primer[r.Intn(7)] = 'N'
+		}
+		var index1, index2 = string(primer), string(primer)
+		switch x := r.Float64(); {
+		case x > 0.95:
+			index1, index2 = randomSeq(r, 10), randomSeq(r, 10)
+		case x > 0.9:
+			index2 = randomSeq(r, 10)
+		}
+		var name = fmt.Sprintf("@read%d", i)
+		pairs[i].read1 = [4]string{name + "/1", index1 + randomSeq(r, 140), "+", qual}
+		pairs[i].read2 = [4]string{name + "/2", index2 + randomSeq(r, 140), "+", qual}
+	}
+	return pairs
+}
+
+// runWorker run a worker of lane over pairs in batches, drain outputs of samples
+func runWorker(lane *Lane, matcher *Matcher, samples []*Sample, pairs []Pair) *Worker {
+	var sampleMap = make(map[string]*Sample)
+	var done = make(chan struct{})
+	for _, sample := range samples {
+		sample.FQ = make(chan *Chunk, chunkQueue)
+		sample.writeNum = 0
+		sampleMap[sample.SampleID] = sample
+		go func(sample *Sample) {
+			for chunk := range sample.FQ {
+				sample.writeNum += chunk.n
+			}
+			done <- struct{}{}
+		}(sample)
+	}
+	var worker = newWorker(lane, matcher, sampleMap, nil, nil)
+	var batches = make(chan []Pair, batchQueue)
+	go func() {
+		defer close(batches)
+		for i := 0; i < len(pairs); i += batchSize {
+			var j = i + batchSize
+			if j > len(pairs) {
+				j = len(pairs)
+			}
+			batches <- pairs[i:j]
+		}
+	}()
+	worker.run(batches)
+	for _, sample := range samples {
+		close(sample.FQ)
+		<-done
+	}
+	return worker
+}
+
+func TestWorker(t *testing.T) {
+	var lane, samples = testLane(24)
+	var pairs = testPairs(20000, samples)
+	for _, mismatch := range []int{0, 1} {
+		var matcher, err = newMatcher(lane.Index, mismatch, samples)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var worker = runWorker(lane, matcher, samples, pairs)
+		var pe = worker.stat
+		if pe.peNo != uint64(len(pairs)) || pe.hitNo+pe.diffIndex+pe.singleIndex+pe.nonIndex != pe.peNo {
+			t.Errorf("mismatch %d:counts of pairs %+v", mismatch, pe)
+		}
+		var written, exact, corrected uint64
+		for _, sample := range samples {
+			written += sample.writeNum
+			exact += worker.sampleCount[sample.SampleID][0]
+			corrected += worker.sampleCount[sample.SampleID][1]
+		}
+		if written != pe.hitNo || exact+corrected != pe.hitNo {
+			t.Errorf("mismatch %d:written %d exact %d corrected %d of hit %d", mismatch, written, exact, corrected, pe.hitNo)
+		}
+		if (mismatch == 0) != (corrected == 0) {
+			t.Errorf("mismatch %d:corrected %d", mismatch, corrected)
+		}
+	}
+}
+
+func BenchmarkMatch(b *testing.B) {
+	var lane, samples = testLane(24)
+	var pairs = testPairs(batchSize, samples)
+	for _, mismatch := range []int{0, 1} {
+		b.Run(fmt.Sprintf("mismatch%d", mismatch), func(b *testing.B) {
+			var matcher, err = newMatcher(lane.Index, mismatch, samples)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				matcher.match(pairs[i%len(pairs)].read1[1])
+			}
+		})
+	}
+}
+
+// BenchmarkWorker classify pairs and build chunks of samples, without reading and compression
+func BenchmarkWorker(b *testing.B) {
+	var lane, samples = testLane(24)
+	var pairs = testPairs(100000, samples)
+	var matcher, err = newMatcher(lane.Index, 1, samples)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		runWorker(lane, matcher, samples, pairs)
+	}
+	b.ReportMetric(float64(len(pairs))*float64(b.N)/b.Elapsed().Seconds(), "pairs/s")
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	simpleUtil "github.com/liserjrqlxue/simple-util"
+)
+
+// max distinct index sequences counted of each kind, more are counted as other
+const maxIndexCount = 1 << 20
+
+// IndexCount count index sequences of unmatched reads, read1 and read2 separately and as pairs
+type IndexCount struct {
+	Read1, Read2, Pair map[string]uint64
+	// reads not counted for too many distinct index sequences
+	other uint64
+}
+
+func newIndexCount() *IndexCount {
+	return &IndexCount{
+		Read1: make(map[string]uint64),
+		Read2: make(map[string]uint64),
+		Pair:  make(map[string]uint64),
+	}
+}
+
+func (count *IndexCount) inc(m map[string]uint64, key string, n uint64) {
+	if _, ok := m[key]; ok || len(m) < maxIndexCount {
+		m[key] += n
+	} else {
+		count.other += n
+	}
+}
+
+// add counts of other lane
+func (count *IndexCount) add(other *IndexCount) {
+	for _, kind := range []struct{ to, from map[string]uint64 }{
+		{count.Read1, other.Read1},
+		{count.Read2, other.Read2},
+		{count.Pair, other.Pair},
+	} {
+		for key, n := range kind.from {
+			count.inc(kind.to, key, n)
+		}
+	}
+	count.other += other.other
+}
+
+// statFile return statdir/[barcode.]name
+func statFile(name string) string {
+	if *barcode != "" {
+		name = *barcode + "." + name
+	}
+	return filepath.Join(*statDir, name)
+}
+
+// laneUnmatched is index of unmatched reads of one lane, and primer index -> sampleID of samples of the lane,
+// as index length and primers may differ between lanes
+type laneUnmatched struct {
+	lane    string
+	count   *IndexCount
+	primers map[string]string
+	pairs   uint64
+}
+
+func newLaneUnmatched(lane string, pe *PE, samples []*Sample) *laneUnmatched {
+	var primers = make(map[string]string)
+	for _, sample := range samples {
+		primers[sample.NewPrimer] = sample.SampleID
+	}
+	return &laneUnmatched{lane: lane, count: pe.unmatched, primers: primers, pairs: pe.peNo}
+}
+
+// writeUnmatched write topN index sequences of each kind of each lane with nearest primer index of the lane and its distance,
+// fraction is of pairs of the lane
+func writeUnmatched(fileName string, lanes []*laneUnmatched, topN int) {
+	simpleUtil.CheckErr(os.MkdirAll(filepath.Dir(fileName), 0755))
+	var file, err = os.Create(fileName)
+	simpleUtil.CheckErr(err)
+	defer simpleUtil.DeferClose(file)
+	var w = bufio.NewWriter(file)
+	fmt.Fprintln(w, strings.Join([]string{"lane", "read", "index", "count", "fraction", "nearestSample", "nearestPrimer", "distance"}, "\t"))
+	for _, lane := range lanes {
+		var count = lane.count
+		for _, kind := range []struct {
+			name  string
+			count map[string]uint64
+		}{
+			{"read1", count.Read1},
+			{"read2", count.Read2},
+			{"pair", count.Pair},
+		} {
+			for _, index := range topIndex(kind.count, topN) {
+				var samples, nearest, distances []string
+				for _, key := range strings.Split(index, "+") {
+					var sampleID, primer, d = nearestPrimer(key, lane.primers)
+					samples = append(samples, sampleID)
+					nearest = append(nearest, primer)
+					distances = append(distances, fmt.Sprint(d))
+				}
+				fmt.Fprintf(
+					w, "%s\t%s\t%s\t%d\t%f\t%s\t%s\t%s\n",
+					lane.lane, kind.name, index, kind.count[index], fraction(kind.count[index], lane.pairs),
+					strings.Join(samples, "+"), strings.Join(nearest, "+"), strings.Join(distances, "+"),
+				)
+			}
+		}
+		if count.other > 0 {
+			fmt.Fprintf(w, "%s\tother\t\t%d\t%f\t\t\t\n", lane.lane, count.other, fraction(count.other, lane.pairs))
+		}
+	}
+	simpleUtil.CheckErr(w.Flush())
+}
+
+// topIndex return topN keys by count desc
+func topIndex(count map[string]uint64, topN int) (keys []string) {
+	for key := range count {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if count[keys[i]] != count[keys[j]] {
+			return count[keys[i]] > count[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > topN {
+		keys = keys[:topN]
+	}
+	return
+}
+
+// nearestPrimer return sample and primer index nearest to index by Hamming distance, first sampleID on tie
+func nearestPrimer(index string, primers map[string]string) (sampleID, primer string, distance int) {
+	distance = -1
+	for key, id := range primers {
+		var d = hamming(index, key)
+		if distance < 0 || d < distance || (d == distance && id < sampleID) {
+			sampleID, primer, distance = id, key, d
+		}
+	}
+	return
+}
+
+// writeHopping write sample of read1 x sample of read2 matrix of pairs with index of samples,
+// off-diagonal is index hopping, diagonal is hit pairs
+func writeHopping(fileName string, hopping map[[2]string]uint64, samples []string) {
+	simpleUtil.CheckErr(os.MkdirAll(filepath.Dir(fileName), 0755))
+	var file, err = os.Create(fileName)
+	simpleUtil.CheckErr(err)
+	defer simpleUtil.DeferClose(file)
+	var w = bufio.NewWriter(file)
+	fmt.Fprintln(w, "read1\\read2\t"+strings.Join(samples, "\t"))
+	for _, sample1 := range samples {
+		var row = []string{sample1}
+		for _, sample2 := range samples {
+			row = append(row, fmt.Sprint(hopping[[2]string{sample1, sample2}]))
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	simpleUtil.CheckErr(w.Flush())
+}
+
+// BarcodeStat is stat of one barcode, written to [barcode.]stat.json
+type BarcodeStat struct {
+	Barcode     string        `json:"barcode"`
+	Pairs       uint64        `json:"pairs"`
+	Hit         uint64        `json:"hit"`
+	DiffIndex   uint64        `json:"diffIndex"`
+	SingleIndex uint64        `json:"singleIndex"`
+	NonIndex    uint64        `json:"nonIndex"`
+	HitRate     float64       `json:"hitRate"`
+	Samples     []*SampleStat `json:"samples"`
+}
+
+// SampleStat is pairs split to sample of one lane
+type SampleStat struct {
+	SampleID  string  `json:"sampleID"`
+	Lane      string  `json:"lane"`
+	Pairs     uint64  `json:"pairs"`
+	Fraction  float64 `json:"fraction"`
+	Exact     uint64  `json:"exact"`
+	Corrected uint64  `json:"corrected"`
+}
+
+// newBarcodeStat from total of lanes and samples of each lane, in sample order
+func newBarcodeStat(total *PE, sampleOrder []string, sampleInfo map[string][]*Sample) *BarcodeStat {
+	var stat = &BarcodeStat{
+		Barcode:     total.barcode,
+		Pairs:       total.peNo,
+		Hit:         total.hitNo,
+		DiffIndex:   total.diffIndex,
+		SingleIndex: total.singleIndex,
+		NonIndex:    total.nonIndex,
+		HitRate:     fraction(total.hitNo, total.peNo),
+	}
+	for _, sampleID := range sampleOrder {
+		for _, sample := range sampleInfo[sampleID] {
+			stat.Samples = append(stat.Samples, &SampleStat{
+				SampleID:  sample.SampleID,
+				Lane:      sample.lane,
+				Pairs:     sample.hitNum,
+				Fraction:  fraction(sample.hitNum, total.peNo),
+				Exact:     sample.exactNum,
+				Corrected: sample.correctedNum,
+			})
+		}
+	}
+	return stat
+}
+
+func fraction(n, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// write stat as [barcode.]stat.json, [barcode.]stat.tsv of barcode and [barcode.]sample_stat.tsv of samples
+func (stat *BarcodeStat) write() {
+	simpleUtil.CheckErr(os.MkdirAll(*statDir, 0755))
+	var b, err = json.MarshalIndent(stat, "", "  ")
+	simpleUtil.CheckErr(err)
+	simpleUtil.CheckErr(os.WriteFile(statFile("stat.json"), append(b, '\n'), 0644))
+
+	var lines = []string{
+		"barcode\tpairs\thit\tdiffIndex\tsingleIndex\tnonIndex\thitRate",
+		fmt.Sprintf("%s\t%d\t%d\t%d\t%d\t%d\t%f", stat.Barcode, stat.Pairs, stat.Hit, stat.DiffIndex, stat.SingleIndex, stat.NonIndex, stat.HitRate),
+	}
+	simpleUtil.CheckErr(os.WriteFile(statFile("stat.tsv"), []byte(strings.Join(lines, "\n")+"\n"), 0644))
+
+	lines = []string{"barcode\tsampleID\tlane\tpairs\tfraction\texact\tcorrected"}
+	for _, sample := range stat.Samples {
+		lines = append(lines, fmt.Sprintf("%s\t%s\t%s\t%d\t%f\t%d\t%d", stat.Barcode, sample.SampleID, sample.Lane, sample.Pairs, sample.Fraction, sample.Exact, sample.Corrected))
+	}
+	simpleUtil.CheckErr(os.WriteFile(statFile("sample_stat.tsv"), []byte(strings.Join(lines, "\n")+"\n"), 0644))
+}
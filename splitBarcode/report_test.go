@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteUnmatched(t *testing.T) {
+	var count1, count2 = newIndexCount(), newIndexCount()
+	count1.Read1["ACGTACA"] = 3
+	// index of 10 bases of lane L2 is compared only with primers of L2
+	count2.Read1["ACGTACGTAA"] = 2
+	var lanes = []*laneUnmatched{
+		{lane: "L1", count: count1, primers: map[string]string{"ACGTACG": "S1"}, pairs: 10},
+		{lane: "L2", count: count2, primers: map[string]string{"ACGTACGTTT": "S2"}, pairs: 20},
+		// empty lane
+		{lane: "L3", count: newIndexCount(), primers: map[string]string{"TTTTTTT": "S3"}},
+	}
+	lanes[2].count.other = 1
+	var fileName = filepath.Join(t.TempDir(), "unmatched.tsv")
+	writeUnmatched(fileName, lanes, 10)
+	var b, err = os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want = []string{
+		"lane\tread\tindex\tcount\tfraction\tnearestSample\tnearestPrimer\tdistance",
+		"L1\tread1\tACGTACA\t3\t0.300000\tS1\tACGTACG\t1",
+		"L2\tread1\tACGTACGTAA\t2\t0.100000\tS2\tACGTACGTTT\t2",
+		"L3\tother\t\t1\t0.000000\t\t\t",
+	}
+	if got := strings.TrimSuffix(string(b), "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("unmatched.tsv:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
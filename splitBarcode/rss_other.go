@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package main
+
+// peakRSS is not supported
+func peakRSS() (float64, bool) {
+	return 0, false
+}
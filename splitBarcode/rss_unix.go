@@ -0,0 +1,22 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"runtime"
+	"syscall"
+)
+
+// peakRSS return peak resident memory of process in MB
+func peakRSS() (float64, bool) {
+	var usage syscall.Rusage
+	if syscall.Getrusage(syscall.RUSAGE_SELF, &usage) != nil {
+		return 0, false
+	}
+	// Maxrss is in KB on linux, in bytes on darwin
+	var kb = float64(usage.Maxrss)
+	if runtime.GOOS == "darwin" {
+		kb /= 1024
+	}
+	return kb / 1024, true
+}
@@ -2,26 +2,26 @@ package main
 
 import (
 	"bufio"
-	"compress/gzip"
-	"fmt"
+	"github.com/liserjrqlxue/DrugPipeline/xopen"
 	"github.com/liserjrqlxue/simple-util"
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
-	"time"
 )
 
 type PE struct {
 	barcode  string
 	Key      string
 	Fq1, Fq2 string
-	F1, F2   *os.File
-	R1, R2   *gzip.Reader
+	R1, R2   *xopen.Reader
 	S1, S2   *bufio.Scanner
 	// current pe
 	peNo, hitNo, diffIndex, singleIndex, nonIndex uint64
+	// index of unmatched reads
+	unmatched *IndexCount
+	// sample of read1 and read2 -> pairs, diagonal is hit pairs
+	hopping map[[2]string]uint64
 }
 
 func (pe *PE) create(barcode, key, fq1, fq2 string) {
@@ -29,110 +29,114 @@ func (pe *PE) create(barcode, key, fq1, fq2 string) {
 	pe.Key = key
 	pe.Fq1 = fq1
 	pe.Fq2 = fq2
-	pe.F1, pe.R1, pe.S1 = readFq(fq1)
-	pe.F2, pe.R2, pe.S2 = readFq(fq2)
+	pe.R1, pe.S1 = readFq(fq1)
+	pe.R2, pe.S2 = readFq(fq2)
+	pe.initStat()
+}
+
+// initStat init counts of index of pe
+func (pe *PE) initStat() {
+	pe.unmatched = newIndexCount()
+	pe.hopping = make(map[[2]string]uint64)
+}
+
+// countUnmatched count index of read not matched, and index pair
+func (pe *PE) countUnmatched(index Index, seq1, seq2 string, ok1, ok2 bool) {
+	var index1, _ = index.read(seq1)
+	var index2, _ = index.read(seq2)
+	if !ok1 && index1 != "" {
+		pe.unmatched.inc(pe.unmatched.Read1, index1, 1)
+	}
+	if !ok2 && index2 != "" {
+		pe.unmatched.inc(pe.unmatched.Read2, index2, 1)
+	}
+	if index1 != "" && index2 != "" {
+		pe.unmatched.inc(pe.unmatched.Pair, index1+"+"+index2, 1)
+	}
+}
+
+// add counts of other pe
+func (pe *PE) add(other *PE) {
+	pe.peNo += other.peNo
+	pe.hitNo += other.hitNo
+	pe.diffIndex += other.diffIndex
+	pe.singleIndex += other.singleIndex
+	pe.nonIndex += other.nonIndex
+	pe.unmatched.add(other.unmatched)
+	for key, n := range other.hopping {
+		pe.hopping[key] += n
+	}
 }
 
 func (pe *PE) close() {
 	simple_util.CheckErr(pe.R1.Close())
 	simple_util.CheckErr(pe.R2.Close())
-	simple_util.CheckErr(pe.F1.Close())
-	simple_util.CheckErr(pe.F2.Close())
 }
 
 type Sample struct {
-	SampleID                     string
-	barcode                      string
-	primer                       string
-	NewPrimer                    string
-	peKey                        string
-	Fq1, Fq2                     string
-	F1, F2                       *os.File
-	W1, W2                       *gzip.Writer
-	hitNum, writeNum             uint64
-	hitMutex, writeMutex, WMutex sync.Mutex
-	FQ                           chan [2]string
+	SampleID               string
+	lane                   string
+	barcode                string
+	primer                 string
+	NewPrimer              string
+	peKey                  string
+	Fq1, Fq2               string
+	F1, F2                 *os.File
+	W1, W2                 *BlockWriter
+	hitNum, writeNum       uint64
+	exactNum, correctedNum uint64 // pairs matched exactly, or with mismatch of either read
+	FQ                     chan *Chunk
 }
 
-func (sample *Sample) create(item map[string]string, peKey, outdir string) {
+// output to outdir/sampleID[.lane].raw_[12].fq.gz
+func (sample *Sample) create(item map[string]string, peKey, lane, outdir string, index Index) {
 	sample.SampleID = item["sampleID"]
+	sample.lane = lane
 	sample.primer = item["primer"]
-	sample.NewPrimer = sample.primer[:7]
+	sample.NewPrimer, err = index.primer(sample.primer)
+	simple_util.CheckErr(err, "sample["+sample.SampleID+"]")
 	sample.peKey = peKey
-	simple_util.CheckErr(os.MkdirAll(outdir, 0755))
-	sample.Fq1 = filepath.Join(outdir, sample.SampleID+".raw_1.fq.gz")
-	sample.Fq2 = filepath.Join(outdir, sample.SampleID+".raw_2.fq.gz")
-	sample.FQ = make(chan [2]string)
+	var name = sample.SampleID
+	if lane != "" {
+		name += "." + lane
+	}
+	sample.Fq1 = filepath.Join(outdir, name+".raw_1.fq.gz")
+	sample.Fq2 = filepath.Join(outdir, name+".raw_2.fq.gz")
+	sample.FQ = make(chan *Chunk, chunkQueue)
+}
+
+// createOutput create output of unsplit pairs: outdir/[barcode.][lane.]name_[12].fq.gz, reads are not trimmed
+func (sample *Sample) createOutput(name, barcode, lane, outdir string) {
+	sample.SampleID = name
+	sample.lane = lane
+	var prefix string
+	for _, key := range []string{barcode, lane} {
+		if key != "" {
+			prefix += key + "."
+		}
+	}
+	sample.Fq1 = filepath.Join(outdir, prefix+name+"_1.fq.gz")
+	sample.Fq2 = filepath.Join(outdir, prefix+name+"_2.fq.gz")
+	sample.FQ = make(chan *Chunk, chunkQueue)
 }
 
 func (sample *Sample) write(wg *sync.WaitGroup) {
 	defer wg.Done()
 	log.Printf("start %s", sample.SampleID)
+	// created after all samples are checked
+	simple_util.CheckErr(os.MkdirAll(filepath.Dir(sample.Fq1), 0755))
 	sample.F1, sample.W1 = writeFq(sample.Fq1)
 	defer simple_util.DeferClose(sample.F1)
 	defer simple_util.DeferClose(sample.W1)
 	sample.F2, sample.W2 = writeFq(sample.Fq2)
 	defer simple_util.DeferClose(sample.F2)
 	defer simple_util.DeferClose(sample.W2)
-	for FQ := range sample.FQ {
-		sample.WMutex.Lock()
-		_, err = fmt.Fprintln(sample.W1, FQ[0])
+	for chunk := range sample.FQ {
+		_, err := sample.W1.Write(chunk.fq1)
 		simple_util.CheckErr(err, sample.SampleID, "write fq1 error")
-		_, err = fmt.Fprintln(sample.W2, FQ[1])
+		_, err = sample.W2.Write(chunk.fq2)
 		simple_util.CheckErr(err, sample.SampleID, "write fq2 error")
-		sample.WMutex.Unlock()
-		sample.writeMutex.Lock()
-		sample.writeNum++
-		sample.writeMutex.Unlock()
+		sample.writeNum += chunk.n
 	}
 	log.Printf("finis %s", sample.SampleID)
 }
-
-func (sample *Sample) close() {
-	for sample.writeNum < sample.hitNum {
-		log.Printf("wait sample[%s] write finish:%d/%d", sample.SampleID, sample.writeNum, sample.hitNum)
-		time.Sleep(1 * time.Second)
-	}
-	log.Printf("wait sample[%s] write done:%d/%d\tDone", sample.SampleID, sample.writeNum, sample.hitNum)
-	close(sample.FQ)
-}
-
-var n, maxNumGoroutine int
-
-var throttle chan bool
-
-func readFq(path string) (file *os.File, reader *gzip.Reader, scanner *bufio.Scanner) {
-	var err error
-	file, err = os.Open(path)
-	simple_util.CheckErr(err)
-	reader, err = gzip.NewReader(file)
-	simple_util.CheckErr(err)
-	scanner = bufio.NewScanner(reader)
-	return
-}
-
-func writeFq(path string) (file *os.File, writer *gzip.Writer) {
-	var err error
-	file, err = os.Create(path)
-	simple_util.CheckErr(err)
-	writer = gzip.NewWriter(file)
-	simple_util.CheckErr(err)
-	return
-}
-
-func splitReads(read1, read2 [4]string, sample *Sample) {
-	defer func() { <-throttle }()
-	read1[1] = read1[1][8:]
-	read2[1] = read2[1][8:]
-	read1[3] = read1[3][8:]
-	read2[3] = read2[3][8:]
-	var FQ [2]string
-	FQ[0] = strings.Join(read1[:], "\n")
-	FQ[1] = strings.Join(read2[:], "\n")
-	//go func() { sample.FQ <- FQ }()
-	//sample.FQ <- FQ
-	sample.hitMutex.Lock()
-	sample.hitNum++
-	sample.hitMutex.Unlock()
-	go func(sample *Sample) { sample.FQ <- FQ }(sample)
-}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// RunState is state of a run kept in outdir/run.state.json across restarts
+type RunState struct {
+	// deleted temp output -> deletion time
+	Deleted map[string]string `json:"deleted,omitempty"`
+
+	fileName string
+	lock     sync.Mutex
+}
+
+// state of current run, set by main
+var runState *RunState
+
+// LoadRunState load run state of previous run, empty state if fileName not exists
+func LoadRunState(fileName string) (*RunState, error) {
+	var state = &RunState{fileName: fileName}
+	var b, err = os.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// deleted record deletion of temp output
+func (state *RunState) deleted(path string) error {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	if state.Deleted == nil {
+		state.Deleted = make(map[string]string)
+	}
+	state.Deleted[path] = time.Now().Format(time.RFC3339)
+	return state.save()
+}
+
+// present return paths not deleted as temp output, which should exist
+func (state *RunState) present(paths []string) (list []string) {
+	if state == nil {
+		return paths
+	}
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	for _, path := range paths {
+		if _, ok := state.Deleted[path]; !ok {
+			list = append(list, path)
+		}
+	}
+	return
+}
+
+// save write state to temp file and rename, keep previous state on failure
+func (state *RunState) save() error {
+	var b, err = json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(state.fileName+".tmp", append(b, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(state.fileName+".tmp", state.fileName)
+}
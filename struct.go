@@ -1,53 +1,96 @@
 package main
 
 import (
-	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 
-	"github.com/liserjrqlxue/goUtil/textUtil"
 	"github.com/liserjrqlxue/libIM"
 )
 
-func NewInfo(item map[string]string) libIM.Info {
-	return libIM.Info{
-		SampleID: item["sampleID"],
-		Fq1:      item["fq1"],
-		Fq2:      item["fq2"],
+// CreateIMSteps convert tasks of steps to libIM steps, jobs are scripts created by tasks,
+// step without job is dropped and its successors take its prior instead, so order through it is kept
+func CreateIMSteps(steps []StepConfig, taskList map[string]*Task, priorMap map[string][]string) (allSteps []*libIM.Step) {
+	var jobs = make(map[string][]libIM.Job)
+	for _, item := range steps {
+		jobs[item.Name] = taskList[item.Name].imJobs()
 	}
+	var stepMap = make(map[string]*libIM.Step)
+	for _, item := range steps {
+		if jobs[item.Name] == nil {
+			continue
+		}
+		var cfg = item.Item()
+		cfg["prior"] = strings.Join(imPrior(item.Name, priorMap, jobs), ",")
+		var step = libIM.NewStep(cfg)
+		step.JobSh = jobs[item.Name]
+		stepMap[step.Name] = &step
+		allSteps = append(allSteps, &step)
+	}
+	libIM.LinkSteps(stepMap)
+	var step, ok = stepMap[*first]
+	if ok {
+		step.First = 1
+	}
+	return
 }
 
-func ParseInfoIM(input string) (infoMap map[string]libIM.Info) {
-	infoMap = make(map[string]libIM.Info)
-	// parser input list
-	var sampleMap, _ = textUtil.File2MapArray(input, "\t", nil)
-
-	for _, item := range sampleMap {
-		var sampleID = item["sampleID"]
-		info, ok := infoMap[sampleID]
-		if !ok {
-			info = NewInfo(item)
-		} else {
-			fmt.Printf("Dup sampleID:%s", sampleID)
+// imPrior return prior of step with prior without job replaced by their prior recursively
+func imPrior(name string, priorMap map[string][]string, jobs map[string][]libIM.Job) (prior []string) {
+	var seen = make(map[string]bool)
+	var add func(from string)
+	add = func(from string) {
+		if seen[from] {
+			return
+		}
+		seen[from] = true
+		if jobs[from] != nil {
+			prior = append(prior, from)
+			return
 		}
-		infoMap[sampleID] = info
+		for _, p := range priorMap[from] {
+			add(p)
+		}
+	}
+	for _, from := range priorMap[name] {
+		add(from)
 	}
 	return
 }
 
-func ParseStepCfg(cfg string, infoMap map[string]libIM.Info) (allSteps []*libIM.Step) {
-	var stepList, _ = textUtil.File2MapArray(cfg, "\t", nil)
-	var stepMap = make(map[string]*libIM.Step)
-	for _, item := range stepList {
-		var step = libIM.NewStep(item)
-		step.CreateJobs(nil, infoMap, nil, *outDir, *localpath)
-		if step.JobSh != nil {
-			stepMap[step.Name] = &step
-			allSteps = append(allSteps, &step)
+// imMem return mem of task in G rounded up, as im takes integer
+func (task *Task) imMem() int {
+	var mem, _ = strconv.ParseFloat(task.mem, 64)
+	return int(math.Ceil(mem))
+}
+
+// imJobs of task, sorted by script, skipped jobs excluded
+func (task *Task) imJobs() (jobs []libIM.Job) {
+	var scripts []string
+	switch task.TaskType {
+	case "sample":
+		for jobName, script := range task.Scripts {
+			if !task.Skip[jobName] {
+				scripts = append(scripts, script)
+			}
+		}
+	case "barcode":
+		for jobName, script := range task.BarcodeScripts {
+			if !task.Skip[jobName] {
+				scripts = append(scripts, script)
+			}
+		}
+	case "batch":
+		if !task.Skip["batch"] {
+			scripts = append(scripts, task.BatchScript)
 		}
 	}
-	libIM.LinkSteps(stepMap)
-	var step, ok = stepMap[*first]
-	if ok {
-		step.First = 1
+	sort.Strings(scripts)
+	for _, script := range scripts {
+		var job = libIM.NewJob(task.imMem())
+		job.Sh = script
+		jobs = append(jobs, job)
 	}
 	return
 }
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCreateIMStepsSkipped(t *testing.T) {
+	var steps []StepConfig
+	var taskList = make(map[string]*Task)
+	for _, name := range []string{"A", "B", "C", "D"} {
+		steps = append(steps, StepConfig{Name: name, Type: "batch", Mem: 1})
+		taskList[name] = &Task{TaskName: name, TaskType: "batch", BatchScript: "/out/shell/" + name + ".sh", Skip: map[string]bool{}, mem: "1"}
+	}
+	// B and C skipped: A -> B -> C -> D, and A -> D
+	taskList["B"].Skip["batch"] = true
+	taskList["C"].Skip["batch"] = true
+	var priorMap = map[string][]string{"B": {"A"}, "C": {"B"}, "D": {"C", "A"}}
+	var allSteps = CreateIMSteps(steps, taskList, priorMap)
+	var got = make(map[string]string)
+	for _, step := range allSteps {
+		got[step.Name] = strings.Join(step.PriorStep, ",") + "|" + strings.Join(step.NextStep, ",")
+	}
+	var want = map[string]string{"A": "|D", "D": "A|"}
+	if len(got) != len(want) {
+		t.Fatalf("steps:%v want %v", got, want)
+	}
+	for name, link := range want {
+		if got[name] != link {
+			t.Errorf("prior|next of %s:%s want %s", name, got[name], link)
+		}
+	}
+}
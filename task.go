@@ -20,9 +20,24 @@ type Task struct {
 	Scripts        map[string]string
 	BatchScript    string
 	BarcodeScripts map[string]string
+	TaskInputs     []string
+	TaskOutputs    []string
+	TaskTemp       []string
+	TaskDeliver    []string
+	TaskEnv        []string
+	TaskWhen       string
+	TaskScratch    bool
+	Inputs         map[string][]string
+	Outputs        map[string][]string
+	Temp           map[string][]string
+	Deliver        map[string][]string
+	Env            map[string][]string
+	Skip           map[string]bool
 	mem            string
-	thread         string
-	submitArgs     []string
+	// space written by a job as multiple of its input fastq size
+	disk       float64
+	thread     string
+	submitArgs []string
 }
 
 func createStartTask() *Task {
@@ -43,24 +58,38 @@ func createEndTask() *Task {
 	}
 }
 
-func createTask(cfg map[string]string, local string, submitArgs []string) *Task {
+func createTask(step StepConfig, local string, submitArgs []string) *Task {
+	var cfg = step.Item()
 	task := Task{
-		TaskName:       cfg["name"],
+		TaskName:       step.Name,
 		TaskInfo:       cfg,
-		TaskType:       cfg["type"],
-		TaskScript:     filepath.Join(local, "script", cfg["name"]+".sh"),
-		TaskArgs:       strings.Split(cfg["args"], ","),
+		TaskType:       step.Type,
+		TaskScript:     filepath.Join(local, "script", step.Name+".sh"),
+		TaskArgs:       step.Args,
 		TaskToChan:     make(map[string]map[string]*chan string),
 		Scripts:        make(map[string]string),
 		BarcodeScripts: make(map[string]string),
+		TaskInputs:     step.Inputs,
+		TaskOutputs:    step.Outputs,
+		TaskTemp:       step.Temp,
+		TaskDeliver:    step.Deliver,
+		TaskEnv:        step.envList(),
+		TaskWhen:       step.When,
+		TaskScratch:    step.Scratch,
+		Inputs:         make(map[string][]string),
+		Outputs:        make(map[string][]string),
+		Temp:           make(map[string][]string),
+		Deliver:        make(map[string][]string),
+		Env:            make(map[string][]string),
+		Skip:           make(map[string]bool),
 		mem:            cfg["mem"],
+		disk:           step.Disk,
 		thread:         cfg["thread"],
-		submitArgs:     append(submitArgs, "-l", "vf="+cfg["mem"]+"G,p="+cfg["thread"]),
 		End:            true,
 	}
-	if cfg["submitArgs"] != "" {
-		task.submitArgs = append(task.submitArgs, sep.Split(cfg["submitArgs"], -1)...)
-	}
+	// copy to avoid sharing backing array between tasks
+	task.submitArgs = append(append([]string{}, submitArgs...), "-l", "vf="+cfg["mem"]+"G,p="+cfg["thread"])
+	task.submitArgs = append(task.submitArgs, step.SubmitArgs...)
 	return &task
 }
 
@@ -109,69 +138,108 @@ func (task *Task) WaitEnd(info Info) {
 	}
 }
 
-func (task *Task) CreateScripts(info Info) {
+func (task *Task) CreateScripts(info Info, taskList map[string]*Task) {
+	switch task.TaskType {
+	case "sample":
+		task.createSampleScripts(info, taskList)
+	case "batch":
+		task.createBatchScripts(info, taskList)
+	case "barcode":
+		task.createBarcodeScripts(info, taskList)
+	default:
+		log.Fatalf("not support task type:%s of Task[%s]", task.TaskType, task.TaskName)
+	}
+}
+
+// ResolveJobs resolve every job of task as CreateScripts without writing scripts
+func (task *Task) ResolveJobs(info Info, taskList map[string]*Task) {
 	switch task.TaskType {
 	case "sample":
-		task.createSampleScripts(info)
+		for sampleID := range info.SampleMap {
+			task.resolveArgs(info, sampleID, taskList)
+		}
 	case "batch":
-		task.createBatchScripts()
+		task.resolveArgs(info, "batch", taskList)
 	case "barcode":
-		task.createBarcodeScripts(info)
+		for barcode := range info.BarcodeMap {
+			task.resolveArgs(info, barcode, taskList)
+		}
+	default:
+		log.Fatalf("not support task type:%s of Task[%s]", task.TaskType, task.TaskName)
+	}
+}
+
+// resolve args, declared inputs/outputs, env and when of job
+func (task *Task) resolveArgs(info Info, jobName string, taskList map[string]*Task) []string {
+	ctx, err := newArgContext(task, info, jobName, taskList)
+	simple_util.CheckErr(err)
+	if task.TaskWhen != "" {
+		ok, err := ctx.Eval(task.TaskWhen)
+		simple_util.CheckErr(err)
+		task.Skip[jobName] = !ok
+	}
+	env, err := ctx.ResolvePaths(task.TaskEnv)
+	simple_util.CheckErr(err)
+	var sampleID string
+	if ctx.Sample != nil {
+		sampleID = ctx.Sample.sampleID
 	}
+	// layout dirs first, step env can refer or override them
+	task.Env[jobName] = append(layout.env(sampleID), env...)
+	args, err := ctx.ResolveArgs(task.TaskArgs)
+	simple_util.CheckErr(err)
+	inputs, err := ctx.ResolvePaths(task.TaskInputs)
+	simple_util.CheckErr(err)
+	task.Inputs[jobName] = inputs
+	outputs, err := ctx.ResolvePaths(task.TaskOutputs)
+	simple_util.CheckErr(err)
+	task.Outputs[jobName] = outputs
+	temp, err := ctx.ResolvePaths(task.TaskTemp)
+	simple_util.CheckErr(err)
+	task.Temp[jobName] = temp
+	deliver, err := ctx.ResolvePaths(task.TaskDeliver)
+	simple_util.CheckErr(err)
+	task.Deliver[jobName] = deliver
+	return args
 }
 
-func (task *Task) createSampleScripts(info Info) {
-	for sampleID, sampleInfo := range info.SampleMap {
-		script := filepath.Join(*outDir, sampleID, "shell", task.TaskName+".sh")
+func (task *Task) createSampleScripts(info Info, taskList map[string]*Task) {
+	for sampleID := range info.SampleMap {
+		script := filepath.Join(layout.sampleDir(sampleID, "shell"), task.TaskName+".sh")
 		task.Scripts[sampleID] = script
 		var appendArgs []string
 		appendArgs = append(appendArgs, *outDir, *localpath, sampleID)
-		for _, arg := range task.TaskArgs {
-			switch arg {
-			default:
-				appendArgs = append(appendArgs, sampleInfo.info[arg])
-			}
-		}
-		createShell(script, task.TaskScript, appendArgs...)
+		appendArgs = append(appendArgs, task.resolveArgs(info, sampleID, taskList)...)
+		task.createShell(script, sampleID, appendArgs)
 	}
 }
 
-func (task *Task) createBatchScripts() {
-	script := filepath.Join(*outDir, "shell", task.TaskName+".sh")
+func (task *Task) createBatchScripts(info Info, taskList map[string]*Task) {
+	script := filepath.Join(layout.batchDir("shell"), task.TaskName+".sh")
 	task.BatchScript = script
 	var appendArgs []string
 	appendArgs = append(appendArgs, *outDir, *localpath)
-	for _, arg := range task.TaskArgs {
-		switch arg {
-		case "list":
-			appendArgs = append(appendArgs, filepath.Join(*outDir, "input.list"))
-		case "laneInput":
-			appendArgs = append(appendArgs, *lane)
-		default:
-		}
-	}
-	createShell(script, task.TaskScript, appendArgs...)
+	appendArgs = append(appendArgs, task.resolveArgs(info, "batch", taskList)...)
+	task.createShell(script, "batch", appendArgs)
 }
 
-func (task *Task) createBarcodeScripts(info Info) {
-	for barcode, barcodeInfo := range info.BarcodeMap {
-		script := filepath.Join(*outDir, "shell", strings.Join([]string{"barcode", barcode, task.TaskName, "sh"}, "."))
+func (task *Task) createBarcodeScripts(info Info, taskList map[string]*Task) {
+	for barcode := range info.BarcodeMap {
+		script := filepath.Join(layout.batchDir("shell"), strings.Join([]string{"barcode", barcode, task.TaskName, "sh"}, "."))
 		task.BarcodeScripts[barcode] = script
 		var appendArgs []string
 		appendArgs = append(appendArgs, *outDir, *localpath)
-		for _, arg := range task.TaskArgs {
-			switch arg {
-			case "barcode":
-				appendArgs = append(appendArgs, barcode)
-			case "fq1":
-				appendArgs = append(appendArgs, barcodeInfo.fq1)
-			case "fq2":
-				appendArgs = append(appendArgs, barcodeInfo.fq2)
-			case "list":
-				appendArgs = append(appendArgs, barcodeInfo.list)
-			}
-		}
-		createShell(script, task.TaskScript, appendArgs...)
+		appendArgs = append(appendArgs, task.resolveArgs(info, barcode, taskList)...)
+		task.createShell(script, barcode, appendArgs)
+	}
+}
+
+// createShell write job script, run in scratch dir if step set scratch
+func (task *Task) createShell(fileName, jobName string, args []string) {
+	if task.TaskScratch {
+		createScratchShell(fileName, task.TaskScript, task.TaskName+"."+jobName, task.Env[jobName], task.Inputs[jobName], task.Outputs[jobName], args...)
+	} else {
+		createShell(fileName, task.TaskScript, task.Env[jobName], task.Outputs[jobName], args...)
 	}
 }
 
@@ -195,6 +263,9 @@ func (task *Task) RunJob(info Info, jobName string, throttle chan bool, taskList
 	log.Printf("Task[%-7s:%s] <- {%s}", task.TaskName, jobName, hjid)
 	var jid = task.TaskName + "[" + jobName + "]"
 	jid = task.RunScript(jobName, hjid, jid, throttle)
+	if tempCleaner != nil {
+		tempCleaner.done(task.TaskName, jobName)
+	}
 	task.SetEnd(info, jid, jobName, taskList)
 }
 
@@ -208,7 +279,11 @@ func (task *Task) RunScript(jobName, depJID, jid string, throttle chan bool) str
 	case "batch":
 		script = task.BatchScript
 	}
-	if simple_util.FileExists(script + ".complete") {
+	if task.Skip[jobName] {
+		log.Printf("skip Task[%-7s:%s]:when %s", task.TaskName, jobName, task.TaskWhen)
+		return ""
+	}
+	if simple_util.FileExists(script+".complete") && verifyOutputs(runState.present(task.Outputs[jobName])) == nil {
 		log.Printf("skip complete script:%s", script)
 		return ""
 	}
@@ -216,12 +291,17 @@ func (task *Task) RunScript(jobName, depJID, jid string, throttle chan bool) str
 	case "sge":
 		jid = simple_util.Submit(script, depJID, task.submitArgs, nil)
 	default:
+		if diskWatchdog != nil {
+			diskWatchdog.wait(diskWatchdog.need(task.TaskType, jobName, task.disk))
+		}
 		throttle <- true
 		log.Printf("Run Task[%-7s:%s]:%s", task.TaskName, jobName, script)
 		if *dryRun {
 			time.Sleep(10 * time.Second)
 		} else {
-			simple_util.CheckErr(simple_util.RunCmd("bash", script))
+			var msg = "Task[" + task.TaskName + ":" + jobName + "] failed:" + script
+			simple_util.CheckErr(simple_util.RunCmd("bash", script), msg)
+			simple_util.CheckErr(verifyOutputs(task.Outputs[jobName]), msg)
 		}
 		<-throttle
 	}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// TempCleaner delete temp outputs once every job reading them succeeded
+type TempCleaner struct {
+	// temp output -> consumer jobs not finished
+	pending map[string]int
+	// consumer job -> temp outputs it reads
+	consumers map[string][]string
+	state     *RunState
+	lock      sync.Mutex
+}
+
+// cleaner of current run, nil for -keepTemp and modes not run by us
+var tempCleaner *TempCleaner
+
+// NewTempCleaner find consumer jobs of every temp output by declared inputs
+func NewTempCleaner(taskList map[string]*Task, state *RunState) *TempCleaner {
+	var cleaner = &TempCleaner{
+		pending:   make(map[string]int),
+		consumers: make(map[string][]string),
+		state:     state,
+	}
+	for taskName, task := range taskList {
+		for jobName, temps := range task.Temp {
+			if task.Skip[jobName] {
+				continue
+			}
+			for _, temp := range temps {
+				var consumers = findConsumers(temp, taskName, taskList)
+				if len(consumers) == 0 {
+					log.Printf("Warning: temp output of Task[%s:%s] not read by any job, kept:%s", taskName, jobName, temp)
+					continue
+				}
+				cleaner.pending[temp] = len(consumers)
+				for _, consumer := range consumers {
+					cleaner.consumers[consumer] = append(cleaner.consumers[consumer], temp)
+				}
+			}
+		}
+	}
+	return cleaner
+}
+
+// findConsumers return sorted jobs, except jobs of fromTask, whose declared inputs match path
+func findConsumers(path, fromTask string, taskList map[string]*Task) (consumers []string) {
+	for taskName, task := range taskList {
+		if taskName == fromTask {
+			continue
+		}
+		for jobName, inputs := range task.Inputs {
+			for _, input := range inputs {
+				if ok, _ := filepath.Match(input, path); ok || input == path {
+					consumers = append(consumers, taskName+":"+jobName)
+					break
+				}
+			}
+		}
+	}
+	sort.Strings(consumers)
+	return
+}
+
+// done mark job succeeded, delete temp outputs whose consumers all succeeded
+func (cleaner *TempCleaner) done(taskName, jobName string) {
+	cleaner.lock.Lock()
+	defer cleaner.lock.Unlock()
+	for _, temp := range cleaner.consumers[taskName+":"+jobName] {
+		cleaner.pending[temp]--
+		if cleaner.pending[temp] > 0 {
+			continue
+		}
+		var err = os.Remove(temp)
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("Warning: can not delete temp output:%v", err)
+			continue
+		}
+		log.Printf("delete temp output:%s", temp)
+		if err = cleaner.state.deleted(temp); err != nil {
+			log.Printf("Warning: can not save run state:%v", err)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/liserjrqlxue/goUtil/simpleUtil"
+)
+
+// default index of splitBarcode, override by indexStart, indexLength and trim columns
+var defaultIndex = rowIndex{Start: 0, Length: 7, Trim: 8}
+
+// rowIndex is where splitBarcode reads index of reads of a row and bases trimmed, same for rows of one barcode and lane
+type rowIndex struct {
+	Start, Length, Trim int
+}
+
+// columns required by barcode steps
+var barcodeColumns = []string{"barcode", "primer", "fq1", "fq2"}
+
+// InputRow is one row of input list with line number
+type InputRow struct {
+	Line int
+	Item map[string]string
+}
+
+// InputError collect all problems of input list
+type InputError struct {
+	File     string
+	Problems []string
+}
+
+func (e *InputError) add(line int, format string, a ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf("%s:%d: %s", e.File, line, fmt.Sprintf(format, a...)))
+}
+
+func (e *InputError) Error() string {
+	return fmt.Sprintf("invalid input list, %d problems:\n%s", len(e.Problems), strings.Join(e.Problems, "\n"))
+}
+
+// readInputList read tab separated input list, or SampleSheet.csv and xlsx, with -columns mapping
+func readInputList(input string, inputErr *InputError) (title []string, rows []InputRow) {
+	if isSheet(input) {
+		title, rows = readSheet(input, inputErr)
+	} else {
+		title, rows = readInputTsv(input, inputErr)
+	}
+	return mapColumns(title, rows, isSheet(input), inputErr), rows
+}
+
+// readInputTsv read tab separated input list, row with wrong column number is reported
+func readInputTsv(input string, inputErr *InputError) (title []string, rows []InputRow) {
+	file, err := os.Open(input)
+	if err != nil {
+		inputErr.add(0, "%v", err)
+		return
+	}
+	defer simpleUtil.DeferClose(file)
+
+	var scanner = bufio.NewScanner(file)
+	var lineNo int
+	for scanner.Scan() {
+		lineNo++
+		var line = scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var array = strings.Split(line, "\t")
+		for i := range array {
+			array[i] = strings.TrimSpace(array[i])
+		}
+		if title == nil {
+			title = array
+			continue
+		}
+		if len(array) != len(title) {
+			inputErr.add(lineNo, "%d columns, title has %d", len(array), len(title))
+			continue
+		}
+		var item = make(map[string]string)
+		for i, key := range title {
+			item[key] = array[i]
+		}
+		rows = append(rows, InputRow{Line: lineNo, Item: item})
+	}
+	if err = scanner.Err(); err != nil {
+		inputErr.add(lineNo, "%v", err)
+	}
+	return
+}
+
+// requiredColumns of input list for steps: sampleID, columns of barcode steps and {sample.COL} placeholders
+func requiredColumns(steps []StepConfig) (columns []string) {
+	var required = map[string]bool{"sampleID": true}
+	for _, step := range steps {
+		if step.Type == "barcode" {
+			for _, column := range barcodeColumns {
+				required[column] = true
+			}
+		}
+		var templates = append(append(append(append([]string{step.When}, step.Args...), step.Inputs...), step.Outputs...), step.envList()...)
+		for _, template := range templates {
+			for _, m := range placeholder.FindAllStringSubmatch(template, -1) {
+				var keys = strings.Split(m[1], ".")
+				if len(keys) == 2 && keys[0] == "sample" {
+					if !sampleFields[keys[1]] {
+						required[keys[1]] = true
+					}
+				}
+			}
+		}
+	}
+	for column := range required {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return
+}
+
+// ValidateInput check input list against steps before any directory is created, report all problems together
+func ValidateInput(input string, steps []StepConfig) (title []string, rows []InputRow, err error) {
+	var inputErr = &InputError{File: input}
+	title, rows = readInputList(input, inputErr)
+	if title == nil {
+		inputErr.add(0, "empty input list")
+		return nil, nil, inputErr
+	}
+
+	var columns = requiredColumns(steps)
+	for _, column := range columns {
+		if !contains(title, column) {
+			inputErr.add(1, "missing column:%s", column)
+		}
+	}
+	var hasBarcode = contains(columns, "barcode")
+
+	var readable = make(map[string]error)
+	// sampleID -> lane -> line
+	var sampleLane = make(map[string]map[string]int)
+	var sampleRow = make(map[string]InputRow)
+	// barcode -> lane -> first row
+	var barcodeRow = make(map[string]map[string]InputRow)
+	// barcode -> indexStart:primer index -> sampleID
+	var barcodeIndex = make(map[string]map[string]string)
+	for _, row := range rows {
+		var item = row.Item
+		for _, column := range columns {
+			if item[column] == "" && contains(title, column) {
+				inputErr.add(row.Line, "empty %s", column)
+			}
+		}
+		var sampleID = item["sampleID"]
+		var lane = item["lane"]
+		if sampleLane[sampleID] == nil {
+			sampleLane[sampleID] = make(map[string]int)
+			sampleRow[sampleID] = row
+		}
+		if line, ok := sampleLane[sampleID][lane]; ok && sampleID != "" {
+			inputErr.add(row.Line, "dup sampleID:%s lane:%s, first at line %d", sampleID, lane, line)
+		} else {
+			sampleLane[sampleID][lane] = row.Line
+		}
+		var first = sampleRow[sampleID]
+		for _, column := range []string{"barcode", "primer"} {
+			if item[column] != first.Item[column] {
+				inputErr.add(row.Line, "%s of sample[%s] differ from line %d:[%s]vs[%s]", column, sampleID, first.Line, item[column], first.Item[column])
+			}
+		}
+
+		for _, column := range []string{"fq1", "fq2"} {
+			var path = item[column]
+			if path == "" || !contains(columns, column) {
+				continue
+			}
+			err, ok := readable[path]
+			if !ok {
+				err = checkReadable(path)
+				readable[path] = err
+			}
+			if err != nil {
+				inputErr.add(row.Line, "%s of sample[%s]:%v", column, sampleID, err)
+			}
+		}
+
+		if !hasBarcode {
+			continue
+		}
+		var barcode = item["barcode"]
+		if barcodeRow[barcode] == nil {
+			barcodeRow[barcode] = make(map[string]InputRow)
+			barcodeIndex[barcode] = make(map[string]string)
+		}
+		if first, ok := barcodeRow[barcode][lane]; !ok {
+			barcodeRow[barcode][lane] = row
+		} else if first.Item["fq1"] != item["fq1"] || first.Item["fq2"] != item["fq2"] {
+			inputErr.add(
+				row.Line, "barcode[%s] lane[%s] fastq [%s %s] differ from line %d [%s %s]",
+				barcode, lane, item["fq1"], item["fq2"], first.Line, first.Item["fq1"], first.Item["fq2"],
+			)
+		}
+		var index, err = parseRowIndex(item)
+		if err != nil {
+			inputErr.add(row.Line, "%v", err)
+			continue
+		}
+		if first := barcodeRow[barcode][lane]; first.Line != row.Line {
+			// first row is valid or reported already
+			if firstIndex, err := parseRowIndex(first.Item); err == nil && firstIndex != index {
+				inputErr.add(
+					row.Line, "index of sample[%s] %+v differ from line %d %+v, should be same in barcode[%s] lane[%s]",
+					sampleID, index, first.Line, firstIndex, barcode, lane,
+				)
+			}
+		}
+		var primer = item["primer"]
+		if len(primer) < index.Length {
+			inputErr.add(row.Line, "primer[%s] of sample[%s] shorter than %d", primer, sampleID, index.Length)
+			continue
+		}
+		var key = fmt.Sprintf("%d:%s", index.Start, primer[:index.Length])
+		if other, ok := barcodeIndex[barcode][key]; ok && other != sampleID {
+			inputErr.add(row.Line, "primer[%s] of sample[%s] has same index[%s] as sample[%s] in barcode[%s]", primer, sampleID, primer[:index.Length], other, barcode)
+		} else {
+			barcodeIndex[barcode][key] = sampleID
+		}
+	}
+	if len(inputErr.Problems) > 0 {
+		return title, rows, inputErr
+	}
+	return title, rows, nil
+}
+
+// parseRowIndex of row, from index columns or defaultIndex
+func parseRowIndex(item map[string]string) (index rowIndex, err error) {
+	index = defaultIndex
+	for _, column := range []struct {
+		name  string
+		value *int
+		min   int
+	}{
+		{"indexStart", &index.Start, 0},
+		{"indexLength", &index.Length, 1},
+		{"trim", &index.Trim, 0},
+	} {
+		if item[column.name] == "" {
+			continue
+		}
+		var value, err = strconv.Atoi(item[column.name])
+		if err != nil || value < column.min {
+			return index, fmt.Errorf("%s of sample[%s] should be integer not less than %d:%q", column.name, item["sampleID"], column.min, item[column.name])
+		}
+		*column.value = value
+	}
+	return
+}
+
+func checkReadable(path string) error {
+	var file, err = os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer simpleUtil.DeferClose(file)
+	var fileInfo os.FileInfo
+	if fileInfo, err = file.Stat(); err != nil {
+		return err
+	}
+	if fileInfo.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
+	return nil
+}
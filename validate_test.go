@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateInputIndex(t *testing.T) {
+	var dir = t.TempDir()
+	var fq = filepath.Join(dir, "L1.fq.gz")
+	if err := os.WriteFile(fq, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var steps = []StepConfig{{Name: "split", Type: "barcode"}}
+	var tests = []struct {
+		rows []string
+		err  []string
+	}{
+		{[]string{"S1\tB1\tL1\tACGTACGTT\t\t\t", "S2\tB1\tL1\tTTGTACGTT\t\t\t"}, nil},
+		// same index at different start of other lane
+		{[]string{"S1\tB1\tL1\tACGTACGTT\t0\t\t", "S2\tB1\tL2\tACGTACGAA\t2\t\t"}, nil},
+		{
+			[]string{"S1\tB1\tL1\tACGTACGTT\t0\t\t", "S2\tB1\tL1\tTTGTACGTT\t2\t\t"},
+			[]string{":3: index of sample[S2] {Start:2 Length:7 Trim:8} differ from line 2 {Start:0 Length:7 Trim:8}"},
+		},
+		{
+			[]string{"S1\tB1\tL1\tACGTACGTT\t\t8\t", "S2\tB1\tL1\tTTGTACGTT\t\t8\t0", "S3\tB1\tL1\tACGTACGTA\t\t8\t"},
+			[]string{":3: index of sample[S2] {Start:0 Length:8 Trim:0} differ", ":4: primer[ACGTACGTA] of sample[S3] has same index[ACGTACGT] as sample[S1]"},
+		},
+		{
+			[]string{"S1\tB1\tL1\tACGTACGTT\t-1\t\t", "S2\tB1\tL1\tACG\t\t\t"},
+			[]string{":2: indexStart of sample[S1] should be integer not less than 0", ":3: primer[ACG] of sample[S2] shorter than 7"},
+		},
+	}
+	for i, test := range tests {
+		var input = filepath.Join(dir, "input.list")
+		var lines = []string{"sampleID\tbarcode\tlane\tprimer\tindexStart\tindexLength\ttrim\tfq1\tfq2"}
+		for _, row := range test.rows {
+			lines = append(lines, row+"\t"+fq+"\t"+fq)
+		}
+		if err := os.WriteFile(input, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		var _, _, err = ValidateInput(input, steps)
+		if len(test.err) == 0 {
+			if err != nil {
+				t.Errorf("%d:%v", i, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%d:no error, want %v", i, test.err)
+			continue
+		}
+		for _, want := range test.err {
+			if !strings.Contains(err.Error(), input+want) {
+				t.Errorf("%d:%v\nwant %s", i, err, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package xopen
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/flate"
+)
+
+// bgzfReader inflate BGZF blocks in parallel: blocks are read in order, inflated by goroutines,
+// at most threads blocks in flight, and returned in order
+type bgzfReader struct {
+	// inflated blocks in order of input
+	blocks chan chan inflated
+	cur    []byte
+	err    error
+	stop   chan struct{}
+	once   sync.Once
+}
+
+type inflated struct {
+	data []byte
+	err  error
+}
+
+// flate readers reused between blocks
+var inflaters sync.Pool
+
+func newBGZFReader(r io.Reader, threads int) *bgzfReader {
+	var reader = &bgzfReader{
+		blocks: make(chan chan inflated, threads),
+		stop:   make(chan struct{}),
+	}
+	go reader.readBlocks(r)
+	return reader
+}
+
+// readBlocks read blocks of r and start inflating each
+func (reader *bgzfReader) readBlocks(r io.Reader) {
+	defer close(reader.blocks)
+	for offset := int64(0); ; {
+		var block, err = readBlock(r)
+		if err == io.EOF {
+			return
+		}
+		var result = make(chan inflated, 1)
+		select {
+		case reader.blocks <- result:
+		case <-reader.stop:
+			return
+		}
+		if err != nil {
+			result <- inflated{err: fmt.Errorf("BGZF block at offset %d:%v", offset, err)}
+			return
+		}
+		offset += int64(len(block))
+		go func() {
+			var data, err = inflate(block)
+			result <- inflated{data: data, err: err}
+		}()
+	}
+}
+
+// readBlock read one whole block, io.EOF at end of input
+func readBlock(r io.Reader) ([]byte, error) {
+	var header = make([]byte, 12)
+	if n, err := io.ReadFull(r, header); err != nil {
+		if n == 0 && err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	if header[0] != 0x1f || header[1] != 0x8b || header[2] != 8 || header[3]&0x04 == 0 {
+		return nil, fmt.Errorf("not a BGZF block")
+	}
+	var extra = make([]byte, binary.LittleEndian.Uint16(header[10:]))
+	if _, err := io.ReadFull(r, extra); err != nil {
+		return nil, err
+	}
+	var bsize = -1
+	for i := 0; i+4 <= len(extra); {
+		var length = int(binary.LittleEndian.Uint16(extra[i+2:]))
+		if extra[i] == 'B' && extra[i+1] == 'C' && length == 2 && i+6 <= len(extra) {
+			bsize = int(binary.LittleEndian.Uint16(extra[i+4:])) + 1
+			break
+		}
+		i += 4 + length
+	}
+	if bsize < len(header)+len(extra)+8 {
+		return nil, fmt.Errorf("no BC subfield or bad BSIZE")
+	}
+	var block = make([]byte, bsize)
+	copy(block, header)
+	copy(block[len(header):], extra)
+	if _, err := io.ReadFull(r, block[len(header)+len(extra):]); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// inflate block and check CRC32 and ISIZE of its trailer
+func inflate(block []byte) ([]byte, error) {
+	var xlen = int(binary.LittleEndian.Uint16(block[10:]))
+	var cdata = block[12+xlen : len(block)-8]
+	var trailer = block[len(block)-8:]
+	var size = binary.LittleEndian.Uint32(trailer[4:])
+	var fr io.ReadCloser
+	if v := inflaters.Get(); v != nil {
+		fr = v.(io.ReadCloser)
+		if err := fr.(flate.Resetter).Reset(bytes.NewReader(cdata), nil); err != nil {
+			return nil, err
+		}
+	} else {
+		fr = flate.NewReader(bytes.NewReader(cdata))
+	}
+	defer inflaters.Put(fr)
+	var data = make([]byte, size)
+	if _, err := io.ReadFull(fr, data); err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(data) != binary.LittleEndian.Uint32(trailer) {
+		return nil, fmt.Errorf("CRC32 mismatch")
+	}
+	return data, nil
+}
+
+func (reader *bgzfReader) Read(p []byte) (int, error) {
+	for len(reader.cur) == 0 {
+		if reader.err != nil {
+			return 0, reader.err
+		}
+		var result, ok = <-reader.blocks
+		if !ok {
+			reader.err = io.EOF
+			continue
+		}
+		var block = <-result
+		reader.cur, reader.err = block.data, block.err
+	}
+	var n = copy(p, reader.cur)
+	reader.cur = reader.cur[n:]
+	return n, nil
+}
+
+// Close stop reading blocks
+func (reader *bgzfReader) Close() error {
+	reader.once.Do(func() { close(reader.stop) })
+	return nil
+}
@@ -0,0 +1,78 @@
+package xopen
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/flate"
+)
+
+// bgzfBlock compress data as one BGZF block
+func bgzfBlock(t *testing.T, data []byte) []byte {
+	var buf = bytes.NewBuffer([]byte{0x1f, 0x8b, 8, 4, 0, 0, 0, 0, 0, 0xff, 6, 0, 'B', 'C', 2, 0, 0, 0})
+	var fw, err = flate.NewWriter(buf, flate.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(data)
+	fw.Close()
+	var block = buf.Bytes()
+	block = binary.LittleEndian.AppendUint32(block, crc32.ChecksumIEEE(data))
+	block = binary.LittleEndian.AppendUint32(block, uint32(len(data)))
+	binary.LittleEndian.PutUint16(block[16:], uint16(len(block)-1))
+	return block
+}
+
+func TestBGZFReader(t *testing.T) {
+	var input, data bytes.Buffer
+	for i := 0; i < 50; i++ {
+		var line = []byte(strings.Repeat(string(rune('a'+i%26)), 1000+i) + "\n")
+		data.Write(line)
+		input.Write(bgzfBlock(t, line))
+	}
+	// EOF marker
+	input.Write(bgzfBlock(t, nil))
+	for _, threads := range []int{1, 4} {
+		var reader, err = NewReader(bytes.NewReader(input.Bytes()), threads)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reader.Format != BGZF {
+			t.Fatalf("format:%s", reader.Format)
+		}
+		out, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, data.Bytes()) {
+			t.Errorf("threads %d:read %d bytes, want %d", threads, len(out), data.Len())
+		}
+		reader.Close()
+	}
+}
+
+func TestBGZFReaderCorrupt(t *testing.T) {
+	var block = bgzfBlock(t, []byte("@r1\nACGT\n+\nIIII\n"))
+	var badCRC = append([]byte{}, block...)
+	badCRC[len(badCRC)-8]++
+	var tests = map[string][]byte{
+		"CRC32 mismatch": append(append([]byte{}, block...), badCRC...),
+		"unexpected EOF": append(append([]byte{}, block...), block[:len(block)-3]...),
+		"not a BGZF":     append(append([]byte{}, block...), 0x1f, 0x8b, 8, 0, 0, 0, 0, 0, 0, 0xff, 0, 0),
+	}
+	for want, input := range tests {
+		var reader, err = NewReader(bytes.NewReader(input), 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = io.ReadAll(reader)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error:%v want %s", err, want)
+		}
+		reader.Close()
+	}
+}
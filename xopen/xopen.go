@@ -0,0 +1,145 @@
+// Package xopen open plain or compressed input by its magic bytes, for Go-native steps of the pipeline
+package xopen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+)
+
+// Format of input
+type Format int
+
+const (
+	Plain Format = iota
+	Gzip
+	BGZF
+	Zstd
+)
+
+func (format Format) String() string {
+	return [...]string{"plain", "gzip", "BGZF", "zstd"}[format]
+}
+
+// size of blocks decompressed ahead of reading
+const readAhead = 1 << 20
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	bzip2Magic = []byte("BZh")
+	xzMagic    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+)
+
+// Detect format of head of input, BGZF is gzip with BC subfield in extra field, error for unsupported compression
+func Detect(head []byte) (Format, error) {
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		// FEXTRA, XLEN and first subfield BC of BGZF
+		if len(head) >= 16 && head[3]&0x04 != 0 && head[12] == 'B' && head[13] == 'C' {
+			return BGZF, nil
+		}
+		return Gzip, nil
+	case bytes.HasPrefix(head, zstdMagic):
+		return Zstd, nil
+	case bytes.HasPrefix(head, bzip2Magic):
+		return Plain, fmt.Errorf("bzip2 is not supported")
+	case bytes.HasPrefix(head, xzMagic):
+		return Plain, fmt.Errorf("xz is not supported")
+	}
+	return Plain, nil
+}
+
+// Reader is decompressed input
+type Reader struct {
+	io.Reader
+	Format Format
+	// closed in reverse order
+	closers []io.Closer
+}
+
+// NewReader decompress r by format detected, multi-member gzip and BGZF are read as one stream:
+// blocks of BGZF are inflated in parallel by threads goroutines,
+// gzip and zstd are decompressed ahead of reading by threads goroutines, as their stream is sequential
+func NewReader(r io.Reader, threads int) (*Reader, error) {
+	if threads < 1 {
+		threads = 1
+	}
+	var buffered = bufio.NewReader(r)
+	// EOF of short input is plain
+	var head, err = buffered.Peek(16)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	format, err := Detect(head)
+	if err != nil {
+		return nil, err
+	}
+	var reader = &Reader{Format: format}
+	switch format {
+	case BGZF:
+		var bgzf = newBGZFReader(buffered, threads)
+		reader.Reader = bgzf
+		reader.closers = append(reader.closers, bgzf)
+	case Gzip:
+		gz, err := pgzip.NewReaderN(buffered, readAhead, threads)
+		if err != nil {
+			return nil, err
+		}
+		gz.Multistream(true)
+		reader.Reader = gz
+		reader.closers = append(reader.closers, gz)
+	case Zstd:
+		zr, err := zstd.NewReader(buffered, zstd.WithDecoderConcurrency(threads))
+		if err != nil {
+			return nil, err
+		}
+		reader.Reader = zr
+		reader.closers = append(reader.closers, zstdCloser{zr})
+	default:
+		reader.Reader = buffered
+	}
+	return reader, nil
+}
+
+// Open file of path by NewReader, "-" for stdin
+func Open(path string, threads int) (*Reader, error) {
+	if path == "-" {
+		return NewReader(os.Stdin, threads)
+	}
+	var file, err = os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := NewReader(file, threads)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s:%v", path, err)
+	}
+	reader.closers = append([]io.Closer{file}, reader.closers...)
+	return reader, nil
+}
+
+// Close decompressor and file
+func (reader *Reader) Close() (err error) {
+	for i := len(reader.closers) - 1; i >= 0; i-- {
+		if e := reader.closers[i].Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+type zstdCloser struct {
+	*zstd.Decoder
+}
+
+func (zr zstdCloser) Close() error {
+	zr.Decoder.Close()
+	return nil
+}
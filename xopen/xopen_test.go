@@ -0,0 +1,74 @@
+package xopen
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestDetect(t *testing.T) {
+	var tests = []struct {
+		head   []byte
+		format Format
+		err    string
+	}{
+		{nil, Plain, ""},
+		{[]byte("@read1\nACGT\n"), Plain, ""},
+		{[]byte{0x1f, 0x8b, 8, 0, 0, 0, 0, 0, 0, 0xff}, Gzip, ""},
+		// FEXTRA without BC subfield
+		{[]byte{0x1f, 0x8b, 8, 4, 0, 0, 0, 0, 0, 0xff, 6, 0, 'X', 'Y', 2, 0}, Gzip, ""},
+		{[]byte{0x1f, 0x8b, 8, 4, 0, 0, 0, 0, 0, 0xff, 6, 0, 'B', 'C', 2, 0}, BGZF, ""},
+		{[]byte{0x28, 0xb5, 0x2f, 0xfd, 0}, Zstd, ""},
+		{[]byte("BZh91AY&SY"), Plain, "bzip2"},
+		{[]byte{0xfd, '7', 'z', 'X', 'Z', 0}, Plain, "xz"},
+	}
+	for _, test := range tests {
+		var format, err = Detect(test.head)
+		if format != test.format || (test.err == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("Detect(%q)=%s,%v want %s,%s", test.head, format, err, test.format, test.err)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	var data = []byte(strings.Repeat("@read\nACGT\n+\nIIII\n", 1000))
+	var dir = t.TempDir()
+	var files = map[Format][]byte{Plain: data}
+	// multi-member gzip
+	var buf bytes.Buffer
+	for _, part := range [][]byte{data[:1000], data[1000:]} {
+		var gw = gzip.NewWriter(&buf)
+		gw.Write(part)
+		gw.Close()
+	}
+	files[Gzip] = append([]byte{}, buf.Bytes()...)
+	var zw, _ = zstd.NewWriter(nil)
+	files[Zstd] = zw.EncodeAll(data, nil)
+	files[BGZF] = append(bgzfBlock(t, data), bgzfBlock(t, nil)...)
+	for format, content := range files {
+		var path = filepath.Join(dir, format.String())
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		var reader, err = Open(path, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(reader)
+		if err != nil || reader.Format != format || !bytes.Equal(got, data) {
+			t.Errorf("%s:read %d bytes as %s,%v want %d", path, len(got), reader.Format, err, len(data))
+		}
+		if err = reader.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := Open(filepath.Join(dir, "none"), 1); err == nil {
+		t.Error("no error of missing file")
+	}
+}